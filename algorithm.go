@@ -0,0 +1,156 @@
+package ratelimitedreader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Algorithm is a pacing strategy, for plugging in custom ones such as
+// priority or billing aware pacing without forking Read. Grant is asked
+// before every read how many of allowed bytes may be read and how long to
+// wait before reading them, and books what it grants. Granting 0 bytes makes
+// Read ask again after the wait. A Limiter is the default Algorithm.
+type Algorithm interface {
+	Grant(allowed int64, now time.Time) (grant int64, wait time.Duration)
+}
+
+// WithAlgorithm paces Read with algorithm instead of the reader's limiter,
+// which keeps pacing TryRead and poll mode reads, where there's no waiting.
+// Tiers and quotas still cap what is asked of algorithm.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(r *RateLimitedReader) {
+		r.algorithm = algorithm
+	}
+}
+
+// Grant books up to one interval's budget of allowed bytes at now, returning
+// how many along with how long to wait before reading them, so custom
+// algorithms can build on a Limiter.
+func (l *Limiter) Grant(allowed int64, now time.Time) (grant int64, wait time.Duration) {
+	iterLimit := l.iterLimit()
+	if iterLimit <= 0 {
+		return allowed, 0
+	}
+
+	grant = min(allowed, iterLimit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return grant, l.book(now.UnixNano(), grant, iterLimit)
+}
+
+// AlgorithmFactory returns an Algorithm pacing a reader at limiter's limit,
+// which UpdateLimit keeps changing.
+type AlgorithmFactory func(limiter *Limiter) Algorithm
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[string]AlgorithmFactory{
+		"interval": func(limiter *Limiter) Algorithm { return limiter },
+		"gcra":     func(limiter *Limiter) Algorithm { return &gcra{limiter: limiter} },
+
+		"legacy-second":   func(limiter *Limiter) Algorithm { return &lastReadPacer{limiter: limiter, window: time.Second} },
+		"legacy-interval": func(limiter *Limiter) Algorithm { return &lastReadPacer{limiter: limiter} },
+	}
+)
+
+// RegisterAlgorithm makes an algorithm available by name to
+// WithAlgorithmName, so config driven systems can pick strategies from
+// strings. The built in "interval" is a Limiter's pacing and "gcra" the
+// generic cell rate algorithm, while "legacy-second" and "legacy-interval"
+// keep the pacing of the v1 to v3 and the v4 to v6 packages. It panics if
+// factory is nil or name is already registered.
+func RegisterAlgorithm(name string, factory AlgorithmFactory) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	if factory == nil {
+		panic("rate-limited-reader: RegisterAlgorithm factory is nil")
+	}
+	if _, dup := algorithms[name]; dup {
+		panic("rate-limited-reader: RegisterAlgorithm called twice for " + name)
+	}
+	algorithms[name] = factory
+}
+
+// WithAlgorithmName is WithAlgorithm with the algorithm registered under
+// name, pacing at the reader's limit. An unknown name leaves the reader
+// paced by its limiter, and makes NewReaderE fail with ErrUnknownAlgorithm.
+func WithAlgorithmName(name string) Option {
+	return func(r *RateLimitedReader) {
+		algorithmsMu.RLock()
+		factory, ok := algorithms[name]
+		algorithmsMu.RUnlock()
+
+		if !ok {
+			r.optErr = fmt.Errorf("%w: %q", ErrUnknownAlgorithm, name)
+			return
+		}
+		r.algorithm = factory(r.limiter)
+	}
+}
+
+// gcra paces by the generic cell rate algorithm: each grant moves the
+// theoretical arrival time on by its cost at the limit, and callers wait
+// until that time comes.
+type gcra struct {
+	limiter *Limiter
+
+	mu  sync.Mutex
+	tat time.Time
+}
+
+func (g *gcra) Grant(allowed int64, now time.Time) (grant int64, wait time.Duration) {
+	limit := g.limiter.Limit()
+	iterLimit := g.limiter.iterLimit()
+	if iterLimit <= 0 {
+		return allowed, 0
+	}
+	grant = min(allowed, iterLimit)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.tat.Before(now) {
+		g.tat = now
+	}
+	wait = g.tat.Sub(now)
+	g.tat = g.tat.Add(time.Duration(mulDiv(grant, int64(time.Second), limit)))
+	return grant, wait
+}
+
+// lastReadPacer paces as the v1 to v6 packages did: each grant is up to a
+// window's budget, and waits for the time it takes at the limit less the time
+// since the last grant, without carrying over time slept too long or too
+// short. window is a second for v1 to v3, and 0, the limiter's interval, for
+// v4 to v6.
+type lastReadPacer struct {
+	limiter *Limiter
+	window  time.Duration
+
+	mu       sync.Mutex
+	lastRead time.Time
+}
+
+func (p *lastReadPacer) Grant(allowed int64, now time.Time) (grant int64, wait time.Duration) {
+	limit := p.limiter.Limit()
+	window := p.window
+	if window == 0 {
+		window = p.limiter.Interval()
+	}
+	budget := mulDiv(limit, int64(window), int64(time.Second))
+	if limit <= 0 || budget <= 0 || p.limiter.Passthrough() {
+		return allowed, 0
+	}
+	grant = min(allowed, budget)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	expected := time.Duration(mulDiv(grant, int64(window), budget))
+	wait = max(expected-now.Sub(p.lastRead), 0)
+	p.lastRead = now.Add(wait)
+	return grant, wait
+}
@@ -0,0 +1,95 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedAlgorithm grants size bytes every period.
+type fixedAlgorithm struct {
+	size   int64
+	period time.Duration
+	next   time.Time
+}
+
+func (a *fixedAlgorithm) Grant(allowed int64, now time.Time) (int64, time.Duration) {
+	wait := max(a.next.Sub(now), 0)
+	a.next = now.Add(wait + a.period)
+	return min(allowed, a.size), wait
+}
+
+func TestRateLimitedReader_Algorithm(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize
+	const partsAmount = 2
+
+	algorithm := &fixedAlgorithm{size: 1024, period: partsAmount * time.Second / (dataSize / 1024)}
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), 0, WithAlgorithm(algorithm))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+}
+
+func TestRateLimitedReader_AlgorithmLimiter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 4
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	// a reader paced by a Limiter as its algorithm paces like one drawing on it
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), 0, WithAlgorithm(NewLimiter(limit)))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+}
+
+func TestRateLimitedReader_AlgorithmName(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 8
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	for _, name := range []string{"interval", "gcra", "legacy-second", "legacy-interval"} {
+		t.Run(name, func(t *testing.T) {
+			ratelimitedReader, err := NewReaderE(bytes.NewReader(make([]byte, dataSize)), limit, WithAlgorithmName(name))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			start := time.Now()
+			read(t, ratelimitedReader, bufferSize, dataSize)
+			assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+		})
+	}
+}
+
+// registerFixed registers "fixed" once however many times tests run.
+var registerFixed sync.Once
+
+func TestRegisterAlgorithm(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 2
+
+	registerFixed.Do(func() {
+		RegisterAlgorithm("fixed", func(*Limiter) Algorithm {
+			return &fixedAlgorithm{size: 1024, period: partsAmount * time.Second / (dataSize / 1024)}
+		})
+	})
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), 0, WithAlgorithmName("fixed"))
+	start := time.Now()
+	read(t, ratelimitedReader, dataSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+}
+
+func TestRateLimitedReader_UnknownAlgorithmName(t *testing.T) {
+	_, err := NewReaderE(bytes.NewReader(nil), 1024, WithAlgorithmName("unknown"))
+	if !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrUnknownAlgorithm)
+	}
+}
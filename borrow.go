@@ -0,0 +1,71 @@
+package ratelimitedreader
+
+import "fmt"
+
+// debt is what a reader borrowed from future intervals and still owes.
+type debt struct {
+	max   int64
+	repay float64
+
+	// owed is how many bytes are still to be repaid
+	owed int64
+	// repaying is set while reads are paced slower to repay
+	repaying bool
+}
+
+// WithBorrowing lets a read wanting more than an interval's budget borrow up
+// to maxDebt bytes from future intervals and read them right away, smoothing
+// over bursty but bounded demand. The debt is repaid by pacing the intervals
+// that follow at a repay fraction below the limit, e.g. 0.5 for half the
+// limit, and only once it's repaid in full can the reader borrow again, so on
+// average the reader keeps to its limit. maxDebt below 1 or a repay fraction
+// outside (0, 1) is ignored, and makes NewReaderE fail with ErrInvalidLimit.
+func WithBorrowing(maxDebt int64, repay float64) Option {
+	return func(r *RateLimitedReader) {
+		if maxDebt < 1 || !(repay > 0 && repay < 1) {
+			r.optErr = fmt.Errorf("%w: borrowing %d bytes repaid at %v", ErrInvalidLimit, maxDebt, repay)
+			return
+		}
+		r.debt = &debt{max: maxDebt, repay: repay}
+	}
+}
+
+// borrow returns how much of want, the bytes wanted beyond the interval's
+// budget, may be read on credit, adding it to the debt.
+func (d *debt) borrow(want int64) int64 {
+	if d.owed > 0 || want <= 0 {
+		return 0
+	}
+
+	d.owed = min(want, d.max)
+	return d.owed
+}
+
+// unborrow takes back n bytes borrowed but never read.
+func (d *debt) unborrow(n int64) {
+	d.owed = max(d.owed-n, 0)
+}
+
+// iterLimit returns iterLimit, lowered to repay the debt while there's one.
+func (d *debt) iterLimit(iterLimit int64) int64 {
+	d.repaying = d.owed > 0
+	if !d.repaying {
+		return iterLimit
+	}
+	return max(int64(float64(iterLimit)*(1-d.repay)), 1)
+}
+
+// use counts n bytes read, which repay the debt by what reading them at the
+// full limit would have read on top while repaying.
+func (d *debt) use(n int) {
+	if d.repaying {
+		d.owed = max(d.owed-int64(float64(n)*d.repay/(1-d.repay)), 0)
+	}
+}
+
+// unborrow takes back n bytes r borrowed but never read.
+func (r *RateLimitedReader) unborrow(n int64) {
+	if r.debt != nil && n > 0 {
+		r.debt.unborrow(n)
+	}
+}
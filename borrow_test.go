@@ -0,0 +1,36 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Borrowing(t *testing.T) {
+	const limit = 10 * 1024
+	const burstSize = limit
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, burstSize*2)), limit, WithBorrowing(burstSize, 0.5))
+
+	// the burst is read on credit right away
+	start := time.Now()
+	read(t, ratelimitedReader, burstSize, burstSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+
+	// then repaid at half the limit, keeping the average to the limit
+	start = time.Now()
+	read(t, ratelimitedReader, burstSize, burstSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
+
+func TestRateLimitedReader_InvalidBorrowing(t *testing.T) {
+	for _, repay := range []float64{0, 1, -0.5} {
+		if _, err := NewReaderE(bytes.NewReader(nil), 1024, WithBorrowing(1024, repay)); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("unexpected error for repay %v: %v expected: %v", repay, err, ErrInvalidLimit)
+		}
+	}
+	if _, err := NewReaderE(bytes.NewReader(nil), 1024, WithBorrowing(0, 0.5)); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrInvalidLimit)
+	}
+}
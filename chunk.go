@@ -0,0 +1,146 @@
+package ratelimitedreader
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxAutoChunkIntervals caps an auto sized grant at this many intervals'
+// budget, keeping pacing within that many intervals of precise.
+const maxAutoChunkIntervals = 4
+
+// WithAutoChunk lets the reader read from the underlying reader in grants of
+// up to maxAutoChunkIntervals intervals' budget at once, sized by the read
+// sizes seen so far, so large buffers cost fewer underlying reads and sleeps.
+// See Stats for the histograms it goes by.
+func WithAutoChunk() Option {
+	return func(r *RateLimitedReader) {
+		r.autoChunk = true
+	}
+}
+
+// WithLatencyAwareChunks sizes grants by how fast the underlying reader
+// actually delivers, so that on a slow source, such as a congested network,
+// each read still completes within about an interval instead of blowing
+// through several, and pacing keeps reacting at interval granularity. Grants
+// grow back as the source speeds up, never past what the limit allows.
+func WithLatencyAwareChunks() Option {
+	return func(r *RateLimitedReader) {
+		r.latencyAware = true
+	}
+}
+
+// WithAlignment rounds grants to multiples of align bytes, e.g. 4096 or
+// 65536, counted from the first byte read, so direct I/O and mmap backed
+// sources get reads aligned to their pages or blocks however odd an
+// interval's budget is. A budget below align grants align bytes, paced over
+// as many intervals as they take. Reads still end where the caller's buffer
+// does. align below 1 is ignored, and makes NewReaderE fail with
+// ErrInvalidLimit.
+func WithAlignment(align int64) Option {
+	return func(r *RateLimitedReader) {
+		if align < 1 {
+			r.optErr = fmt.Errorf("%w: alignment of %d bytes", ErrInvalidLimit, align)
+			return
+		}
+		r.alignment = align
+	}
+}
+
+// WithOnGrant calls fn with the size of every grant, how much the reader is
+// about to read from the underlying reader at once, before reading it, so
+// protocol implementations can size their own frames to what the limit
+// allows. Unlimited reads make no grants. fn runs on the reading goroutine.
+func WithOnGrant(fn func(size int64)) Option {
+	return func(r *RateLimitedReader) {
+		r.onGrant = fn
+	}
+}
+
+// granted reports a grant of size bytes to the WithOnGrant hook, if any.
+func (r *RateLimitedReader) granted(size int64) {
+	if r.onGrant != nil {
+		r.onGrant(size)
+	}
+}
+
+// minLatencyGrantDivisor keeps latency aware grants at no less than this
+// fraction of an interval's budget.
+const minLatencyGrantDivisor = 16
+
+// EffectiveChunkSize returns how much the reader reads per grant right now:
+// an interval's budget, after the integer division of the limit across the
+// intervals in a second, shaped by options such as WithAutoChunk,
+// WithAlignment and WithTimeScale, to check buffer sizes against when
+// tuning. Grace bursts, borrowing and smooth quotas adjust grants read by
+// read on top. 0 means reads aren't paced at all: the limit is 0 or below,
+// the limiter passes through, or, the degenerate case, the limit is below a
+// byte per interval, which NewReaderE rejects. Buffers smaller than the
+// grant are each read in a grant of their own size.
+func (r *RateLimitedReader) EffectiveChunkSize() int64 {
+	limit := r.tieredLimit(r.limiter.iterLimit())
+	if limit <= 0 {
+		return 0
+	}
+	return r.grantSize(r.scaled(limit))
+}
+
+// grantSize returns how much to read from the underlying reader at once.
+func (r *RateLimitedReader) grantSize(limit int64) int64 {
+	grant := limit
+	if r.autoChunk {
+		grant = r.autoChunkSize(limit)
+	}
+
+	if r.latencyAware {
+		// what the source delivers in an interval
+		sourceGrant := float64(r.sourceRate.Load()) * r.limiter.Interval().Seconds()
+		if sourceGrant > 0 && sourceGrant < float64(grant) {
+			grant = max(int64(sourceGrant), limit/minLatencyGrantDivisor, 1)
+		}
+	}
+
+	if r.alignment > 1 {
+		grant = r.alignGrant(grant)
+	}
+	return grant
+}
+
+// alignGrant returns grant shortened to end on an alignment boundary, or
+// stretched to the next one if it doesn't reach it.
+func (r *RateLimitedReader) alignGrant(grant int64) int64 {
+	offset := r.sourceBytes.Load()
+	end := (offset + grant) / r.alignment * r.alignment
+	if end <= offset {
+		end = (offset/r.alignment + 1) * r.alignment
+	}
+	return end - offset
+}
+
+// observeLatency folds a read of n bytes taking latency into the source rate,
+// as a moving average favoring recent reads.
+func (r *RateLimitedReader) observeLatency(n int, latency time.Duration) {
+	if n <= 0 || latency <= 0 {
+		return
+	}
+
+	rate := int64(float64(n) / latency.Seconds())
+	if average := r.sourceRate.Load(); average > 0 {
+		rate = average + (rate-average)/4
+	}
+	r.sourceRate.Store(rate)
+}
+
+// autoChunkSize picks how much to read from the underlying reader at once:
+// as many intervals' budget as consumers usually read in one call, unless
+// the underlying reader usually completes less than one, as then larger
+// reads would come back short anyway.
+func (r *RateLimitedReader) autoChunkSize(limit int64) int64 {
+	// reads in the mode's bucket are under twice its size
+	if readSize := r.readSizes.mode(); readSize > 0 && readSize*2 <= limit {
+		return limit
+	}
+
+	intervals := min(max(r.bufferSizes.mode()/limit, 1), maxAutoChunkIntervals)
+	return intervals * limit
+}
@@ -0,0 +1,143 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_LatencyAwareChunks(t *testing.T) {
+	const dataSize = 5 * 1024   // 5KB
+	const bufferSize = dataSize // one read call
+	const sourceRate = dataSize // a second at the source's own pace
+	const limit = sourceRate * 4
+
+	reader := slowReader{rate: sourceRate}
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithLatencyAwareChunks())
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+
+	// the source delivers a quarter of an interval's budget per interval
+	iterLimit := limit / (1000 / ReadIntervalMilliseconds)
+	readSizes := ratelimitedReader.Stats().ReadSizes
+	var small, large int64
+	for bucket, count := range readSizes {
+		if 1<<bucket < iterLimit/2 {
+			small += count
+		} else {
+			large += count
+		}
+	}
+	if small <= large {
+		t.Fatalf("expected mostly shrunk grants, reads under half an interval's budget: %d others: %d", small, large)
+	}
+}
+
+// slowReader delivers zeros at rate bytes per second.
+type slowReader struct {
+	rate int64
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Duration(int64(len(p)) * int64(time.Second) / r.rate))
+	clear(p)
+	return len(p), nil
+}
+
+// offsetRecorder records the offsets reads from it start at.
+type offsetRecorder struct {
+	reader  *bytes.Reader
+	offset  int64
+	offsets []int64
+}
+
+func (o *offsetRecorder) Read(p []byte) (int, error) {
+	o.offsets = append(o.offsets, o.offset)
+	n, err := o.reader.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func TestRateLimitedReader_Alignment(t *testing.T) {
+	const align = 1024
+	const dataSize = 30 * align
+	const bufferSize = dataSize // one read call
+
+	for _, tc := range []struct {
+		name      string
+		limit     int64
+		alignment int64
+	}{
+		// interval budgets of 2304 bytes, shortened to 2048
+		{"shortened", dataSize * 3 / 2, align},
+		// interval budgets of 576 bytes, stretched to 2048
+		{"stretched", dataSize * 3 / 8, align * 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			source := &offsetRecorder{reader: bytes.NewReader(make([]byte, dataSize))}
+			ratelimitedReader := NewRateLimitedReader(source, tc.limit, WithAlignment(tc.alignment))
+
+			start := time.Now()
+			read(t, ratelimitedReader, bufferSize, dataSize)
+			// the stretched grants are paced at the limit all the same
+			expected := int(math.Round(float64(dataSize) / float64(tc.limit)))
+			assertReadTimes(t, time.Since(start), expected, expected)
+
+			for _, offset := range source.offsets {
+				if offset%tc.alignment != 0 {
+					t.Fatalf("unexpected unaligned read at %d, offsets: %v", offset, source.offsets)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitedReader_OnGrant(t *testing.T) {
+	const iterLimit = 1536
+	const dataSize = 10 * iterLimit
+	const bufferSize = dataSize // one read call
+
+	limit := iterLimit * 1000 / ReadIntervalMilliseconds
+	var grants []int64
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithOnGrant(func(size int64) {
+		grants = append(grants, size)
+	}))
+	read(t, ratelimitedReader, bufferSize, dataSize)
+
+	if len(grants) != dataSize/iterLimit {
+		t.Fatalf("unexpected grants: %v expected %d of %d bytes", grants, dataSize/iterLimit, iterLimit)
+	}
+	for _, grant := range grants {
+		if grant != iterLimit {
+			t.Fatalf("unexpected grants: %v expected %d of %d bytes", grants, dataSize/iterLimit, iterLimit)
+		}
+	}
+}
+
+func TestRateLimitedReader_EffectiveChunkSize(t *testing.T) {
+	intervals := 1000 / ReadIntervalMilliseconds
+	for _, tc := range []struct {
+		name     string
+		limit    int64
+		opts     []Option
+		expected int64
+	}{
+		{"interval budget", 1000 * intervals, nil, 1000},
+		{"rounded down", 1000*intervals + intervals - 1, nil, 1000},
+		{"aligned", 1000 * intervals, []Option{WithAlignment(256)}, 768},
+		{"time scaled", 1000 * intervals, []Option{WithTimeScale(0.5)}, 2000},
+		{"unlimited", 0, nil, 0},
+		{"passthrough", 1000 * intervals, []Option{WithPassthrough()}, 0},
+		{"below a byte per interval", intervals - 1, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), tc.limit, tc.opts...)
+			if size := ratelimitedReader.EffectiveChunkSize(); size != tc.expected {
+				t.Fatalf("unexpected chunk size: %d expected: %d", size, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Command ratebench reads at a matrix of limits, buffer sizes and intervals
+// and reports the throughput achieved against the one configured, along with
+// wakeups and allocations, to help choose settings for a given OS and
+// hardware.
+//
+// Usage:
+//
+//	ratebench [-limits 64K,1M,100M] [-buffers 4K,32K] [-intervals 10ms,50ms,100ms] [-duration 2s]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ratelimitedreader "github.com/idanmadmon/rate-limited-reader"
+)
+
+func main() {
+	limits := flag.String("limits", "64K,1M,100M", "comma separated limits in bytes per second, with an optional K, M or G suffix")
+	buffers := flag.String("buffers", "4K,32K", "comma separated buffer sizes in bytes, with an optional K, M or G suffix")
+	intervals := flag.String("intervals", "10ms,50ms,100ms", "comma separated pacing intervals")
+	duration := flag.Duration("duration", 2*time.Second, "how long to read for each combination")
+	highRes := flag.Bool("highres", false, "ask for a high timer resolution, see WithHighResolutionTimer")
+	flag.Parse()
+
+	if err := run(*limits, *buffers, *intervals, *duration, *highRes); err != nil {
+		fmt.Fprintln(os.Stderr, "ratebench:", err)
+		os.Exit(2)
+	}
+}
+
+func run(limitsFlag, buffersFlag, intervalsFlag string, duration time.Duration, highRes bool) error {
+	limits, err := parseSizes(limitsFlag)
+	if err != nil {
+		return fmt.Errorf("limits: %w", err)
+	}
+	buffers, err := parseSizes(buffersFlag)
+	if err != nil {
+		return fmt.Errorf("buffers: %w", err)
+	}
+	intervals, err := parseDurations(intervalsFlag)
+	if err != nil {
+		return fmt.Errorf("intervals: %w", err)
+	}
+
+	// rows are printed as they're measured, so columns are fixed width
+	const row = "%8s %8s %9v %9s %9s %10s %7v\n"
+	fmt.Printf(row, "limit", "buffer", "interval", "achieved", "accuracy", "wakeups/s", "allocs")
+	for _, limit := range limits {
+		for _, buffer := range buffers {
+			for _, interval := range intervals {
+				opts := []ratelimitedreader.Option{ratelimitedreader.WithInterval(interval)}
+				if highRes {
+					opts = append(opts, ratelimitedreader.WithHighResolutionTimer())
+				}
+
+				overhead := ratelimitedreader.MeasureOverhead(int(buffer), limit, duration, opts...)
+				fmt.Printf(row,
+					formatSize(limit), formatSize(buffer), interval,
+					formatSize(int64(overhead.Throughput)),
+					fmt.Sprintf("%.1f%%", overhead.Throughput/float64(limit)*100),
+					fmt.Sprintf("%.1f", float64(overhead.Wakeups)/overhead.Elapsed.Seconds()),
+					overhead.Allocs)
+			}
+		}
+	}
+	return nil
+}
+
+var sizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+func parseSizes(list string) ([]int64, error) {
+	var sizes []int64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		multiplier := int64(1)
+		for _, s := range sizeSuffixes {
+			if trimmed, ok := strings.CutSuffix(strings.ToUpper(field), s.suffix); ok {
+				field, multiplier = trimmed, s.multiplier
+				break
+			}
+		}
+
+		size, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid size %q", field)
+		}
+		sizes = append(sizes, size*multiplier)
+	}
+	return sizes, nil
+}
+
+func parseDurations(list string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, field := range strings.Split(list, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
+
+func formatSize(size int64) string {
+	for _, s := range sizeSuffixes {
+		if size >= s.multiplier && size%s.multiplier == 0 {
+			return strconv.FormatInt(size/s.multiplier, 10) + s.suffix
+		}
+	}
+	if size >= 1<<10 {
+		return fmt.Sprintf("%.1fK", float64(size)/(1<<10))
+	}
+	return strconv.FormatInt(size, 10)
+}
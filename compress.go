@@ -0,0 +1,71 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Accounting is which bytes of a compressed stream a limit governs.
+type Accounting int
+
+const (
+	// CountCompressed limits the compressed bytes, as read off the wire,
+	// for capping bandwidth.
+	CountCompressed Accounting = iota
+
+	// CountUncompressed limits the decompressed bytes, as handed to the
+	// consumer, for capping how fast data is processed.
+	CountUncompressed
+)
+
+// NewDecodingReader decodes reader with decode, e.g. a decompressor, at limit
+// bytes per second, counting the bytes accounting says. Placing a rate
+// limited reader below or above a decoder by hand does the same, this just
+// makes the choice explicit. Closing the returned reader closes the decoder,
+// if it's an io.Closer, then reader, if it's one.
+func NewDecodingReader(reader io.Reader, decode func(io.Reader) (io.Reader, error), limit int64, accounting Accounting, opts ...Option) (io.ReadCloser, error) {
+	source := io.ReadCloser(io.NopCloser(reader))
+	if closer, ok := reader.(io.ReadCloser); ok {
+		source = closer
+	}
+
+	if accounting == CountCompressed {
+		source = NewRateLimitedReadCloser(source, limit, opts...)
+	}
+
+	decoded, err := decode(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoder io.ReadCloser = decodingReadCloser{Reader: decoded, source: source}
+	if accounting == CountUncompressed {
+		decoder = NewRateLimitedReadCloser(decoder, limit, opts...)
+	}
+	return decoder, nil
+}
+
+// NewGzipReader is NewDecodingReader decompressing gzip.
+func NewGzipReader(reader io.Reader, limit int64, accounting Accounting, opts ...Option) (io.ReadCloser, error) {
+	return NewDecodingReader(reader, func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	}, limit, accounting, opts...)
+}
+
+// decodingReadCloser closes the decoder it reads from, then its source.
+type decodingReadCloser struct {
+	io.Reader
+	source io.Closer
+}
+
+func (d decodingReadCloser) Close() error {
+	if closer, ok := d.Reader.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			d.source.Close()
+			return err
+		}
+	}
+	return d.source.Close()
+}
@@ -0,0 +1,65 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewGzipReader_CountCompressed(t *testing.T) {
+	const dataSize = 1024 * 1024 // 1MB, compresses to almost nothing
+	const limit = 20 * 1024
+
+	reader, err := NewGzipReader(bytes.NewReader(gzipped(t, dataSize)), limit, CountCompressed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	start := time.Now()
+	data, err := io.ReadAll(reader)
+	if err != nil || len(data) != dataSize {
+		t.Fatalf("unexpected read, read: %d error: %v", len(data), err)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestNewGzipReader_CountUncompressed(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader, err := NewGzipReader(bytes.NewReader(gzipped(t, dataSize)), limit, CountUncompressed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	start := time.Now()
+	data, err := io.ReadAll(reader)
+	if err != nil || len(data) != dataSize {
+		t.Fatalf("unexpected read, read: %d error: %v", len(data), err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestNewGzipReader_InvalidData(t *testing.T) {
+	if _, err := NewGzipReader(bytes.NewReader([]byte("not gzip")), 0, CountCompressed); err == nil {
+		t.Fatalf("expected an error for invalid data")
+	}
+}
+
+// gzipped returns dataSize zeros compressed with gzip.
+func gzipped(t *testing.T, dataSize int) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(make([]byte, dataSize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer.Close()
+	return compressed.Bytes()
+}
@@ -0,0 +1,62 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"time"
+)
+
+// Config describes a reader's throttle settings as data, for settings pushed
+// from a central place rather than set in code. See config.proto for its
+// wire form.
+type Config struct {
+	Limit int64
+
+	// Passthrough skips pacing but keeps the limit, see WithPassthrough. A
+	// Limit of 0 or below doesn't pace either.
+	Passthrough bool
+
+	// Interval is the pacing interval, see WithInterval, or 0 for
+	// ReadIntervalMilliseconds.
+	Interval time.Duration
+
+	// Schedule are the tiers the limit steps through, see WithTiers.
+	Schedule []Tier
+
+	// Quota caps the stream per window when set, see WithQuota.
+	Quota *QuotaConfig
+}
+
+// QuotaConfig configures WithQuota, or WithSmoothQuota when Smooth is set.
+type QuotaConfig struct {
+	Bytes  int64
+	Window time.Duration
+	Smooth bool
+}
+
+// NewReaderFromConfig returns a reader over reader with config's settings,
+// followed by opts.
+func NewReaderFromConfig(reader io.Reader, config Config, opts ...Option) *RateLimitedReader {
+	return NewRateLimitedReader(reader, config.Limit, append(config.Options(), opts...)...)
+}
+
+// Options returns the options applying config's settings other than Limit.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Passthrough {
+		opts = append(opts, WithPassthrough())
+	}
+	if c.Interval != 0 {
+		opts = append(opts, WithInterval(c.Interval))
+	}
+	if len(c.Schedule) > 0 {
+		opts = append(opts, WithTiers(c.Schedule))
+	}
+	if c.Quota != nil {
+		if c.Quota.Smooth {
+			opts = append(opts, WithSmoothQuota(c.Quota.Bytes, c.Quota.Window))
+		} else {
+			opts = append(opts, WithQuota(c.Quota.Bytes, c.Quota.Window))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,75 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfig_ProtoRoundTrip(t *testing.T) {
+	config := Config{
+		Limit: 1024,
+		Schedule: []Tier{
+			{After: 0, Limit: 4096},
+			{After: 1 << 20, Limit: 512},
+		},
+		Quota:       &QuotaConfig{Bytes: 1 << 40, Window: 24 * time.Hour, Smooth: true},
+		Passthrough: true,
+		Interval:    100 * time.Millisecond,
+	}
+
+	decoded, err := UnmarshalConfig(config.MarshalProto())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Fatalf("unexpected config: %+v expected: %+v", decoded, config)
+	}
+}
+
+func TestUnmarshalConfig_Wire(t *testing.T) {
+	// limit: 1024, an unknown fixed64 field 15, and schedule: {after: 0, limit: 1}
+	b := []byte{
+		0x08, 0x80, 0x08,
+		0x79, 1, 2, 3, 4, 5, 6, 7, 8,
+		0x12, 0x02, 0x10, 0x01,
+	}
+
+	config, err := UnmarshalConfig(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Config{Limit: 1024, Schedule: []Tier{{After: 0, Limit: 1}}}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("unexpected config: %+v expected: %+v", config, expected)
+	}
+
+	if _, err := UnmarshalConfig(b[:len(b)-1]); !errors.Is(err, ErrMalformedConfig) {
+		t.Fatalf("unexpected error for truncated config: %v expected: %v", err, ErrMalformedConfig)
+	}
+}
+
+func TestNewReaderFromConfig(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 4
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	const interval = 100 * time.Millisecond
+
+	config := Config{
+		Limit:    dataSize, // overridden by the schedule
+		Schedule: []Tier{{After: 0, Limit: limit}},
+		Interval: interval,
+	}
+	ratelimitedReader := NewReaderFromConfig(bytes.NewReader(make([]byte, dataSize)), config)
+	if ratelimitedReader.Limiter().Interval() != interval {
+		t.Fatalf("unexpected interval: %v expected: %v", ratelimitedReader.Limiter().Interval(), interval)
+	}
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+}
@@ -0,0 +1,154 @@
+package ratelimitedreader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// protobuf wire types used by config.proto
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// MarshalProto encodes c as a config.proto LimiterConfig.
+func (c Config) MarshalProto() []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(c.Limit))
+	for _, tier := range c.Schedule {
+		var t []byte
+		t = appendVarintField(t, 1, uint64(tier.After))
+		t = appendVarintField(t, 2, uint64(tier.Limit))
+		b = appendBytesField(b, 2, t)
+	}
+	if c.Quota != nil {
+		var q []byte
+		q = appendVarintField(q, 1, uint64(c.Quota.Bytes))
+		q = appendVarintField(q, 2, uint64(c.Quota.Window))
+		if c.Quota.Smooth {
+			q = appendVarintField(q, 3, 1)
+		}
+		b = appendBytesField(b, 3, q)
+	}
+	if c.Passthrough {
+		b = appendVarintField(b, 4, 1)
+	}
+	b = appendVarintField(b, 5, uint64(c.Interval))
+	return b
+}
+
+// UnmarshalConfig decodes a config.proto LimiterConfig, skipping fields it
+// doesn't know so newer senders can add some.
+func UnmarshalConfig(b []byte) (Config, error) {
+	var c Config
+	err := walkProto(b, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			c.Limit = int64(varint)
+		case 2:
+			var tier Tier
+			err := walkProto(bytes, func(field int, varint uint64, _ []byte) error {
+				switch field {
+				case 1:
+					tier.After = int64(varint)
+				case 2:
+					tier.Limit = int64(varint)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			c.Schedule = append(c.Schedule, tier)
+		case 3:
+			quota := &QuotaConfig{}
+			err := walkProto(bytes, func(field int, varint uint64, _ []byte) error {
+				switch field {
+				case 1:
+					quota.Bytes = int64(varint)
+				case 2:
+					quota.Window = time.Duration(varint)
+				case 3:
+					quota.Smooth = varint != 0
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			c.Quota = quota
+		case 4:
+			c.Passthrough = varint != 0
+		case 5:
+			c.Interval = time.Duration(varint)
+		}
+		return nil
+	})
+	return c, err
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b // proto3 leaves out default values
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// walkProto calls fn with every field of the message in b, passing varint
+// fields' value or length delimited fields' bytes. Fixed width fields are
+// skipped, as config.proto has none.
+func walkProto(b []byte, fn func(field int, varint uint64, bytes []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrMalformedConfig
+		}
+		b = b[n:]
+
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return ErrMalformedConfig
+			}
+			b = b[n:]
+			if err := fn(field, v, nil); err != nil {
+				return err
+			}
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return ErrMalformedConfig
+			}
+			v := b[n : n+int(length)]
+			b = b[n+int(length):]
+			if err := fn(field, 0, v); err != nil {
+				return err
+			}
+		case wireI64:
+			if len(b) < 8 {
+				return ErrMalformedConfig
+			}
+			b = b[8:]
+		case wireI32:
+			if len(b) < 4 {
+				return ErrMalformedConfig
+			}
+			b = b[4:]
+		default:
+			return fmt.Errorf("%w: wire type %d", ErrMalformedConfig, key&7)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+)
+
+// RateLimitedConn paces the reads and writes of a net.Conn. By default each
+// direction has its own limit; a shared conn draws both directions from one
+// budget, for links billed on total bytes.
+type RateLimitedConn struct {
+	net.Conn
+	readLimiter  *Limiter
+	writeLimiter *Limiter
+	shared       bool
+
+	// reads and writes currently drawing on a shared budget
+	activeTransfers atomic.Int64
+
+	// syscallConn passes SyscallConn through, see WithSyscallConn
+	syscallConn bool
+}
+
+// ConnOption configures a RateLimitedConn at construction.
+type ConnOption func(c *RateLimitedConn)
+
+// WithSyscallConn makes SyscallConn return the wrapped conn's raw conn, for
+// kernel fast paths such as sendfile and splice, or socket options. I/O done
+// through the raw conn bypasses the limiter. By default SyscallConn returns
+// errors.ErrUnsupported, guaranteeing every byte is throttled.
+func WithSyscallConn() ConnOption {
+	return func(c *RateLimitedConn) {
+		c.syscallConn = true
+	}
+}
+
+func NewRateLimitedConn(conn net.Conn, readLimit, writeLimit int64, opts ...ConnOption) *RateLimitedConn {
+	c := &RateLimitedConn{
+		Conn:         conn,
+		readLimiter:  NewLimiter(readLimit),
+		writeLimiter: NewLimiter(writeLimit),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewSharedRateLimitedConn returns a conn whose reads and writes together
+// never exceed limit. While both directions are busy each gets half of every
+// interval's budget, so neither can starve the other.
+func NewSharedRateLimitedConn(conn net.Conn, limit int64, opts ...ConnOption) *RateLimitedConn {
+	limiter := NewLimiter(limit)
+	c := &RateLimitedConn{
+		Conn:         conn,
+		readLimiter:  limiter,
+		writeLimiter: limiter,
+		shared:       true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Read reads at most one interval's budget, so a slow peer never keeps it
+// blocked waiting to fill p.
+func (c *RateLimitedConn) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return c.Conn.Read(p)
+	}
+
+	allowedBytes := c.grant(c.readLimiter, int64(len(p)))
+	return c.Conn.Read(p[:allowedBytes])
+}
+
+func (c *RateLimitedConn) Write(p []byte) (n int, err error) {
+	total := 0
+	for total < len(p) {
+		allowedBytes := c.grant(c.writeLimiter, int64(len(p)-total))
+		n, err = c.Conn.Write(p[total : total+int(allowedBytes)])
+		total += n
+		if err != nil {
+			break
+		}
+	}
+
+	return total, err
+}
+
+// SyscallConn returns the wrapped conn's raw conn if WithSyscallConn was
+// given and the wrapped conn has one, and errors.ErrUnsupported otherwise.
+func (c *RateLimitedConn) SyscallConn() (syscall.RawConn, error) {
+	if !c.syscallConn {
+		return nil, errors.ErrUnsupported
+	}
+
+	conn, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return conn.SyscallConn()
+}
+
+// TCPConn returns the TCP conn underneath c for socket tuning such as
+// SetKeepAlive, SetKeepAliveConfig and SetNoDelay, which move no data so
+// aren't throttled. It sees through conns that expose the conn they wrap
+// with NetConn, such as *tls.Conn and proxy protocol wrappers, so tuning
+// survives them too. ok is false if there's no TCP conn underneath.
+func (c *RateLimitedConn) TCPConn() (conn *net.TCPConn, ok bool) {
+	inner := c.Conn
+	for {
+		switch wrapper := inner.(type) {
+		case *net.TCPConn:
+			return wrapper, true
+		case *RateLimitedConn:
+			inner = wrapper.Conn
+		case interface{ NetConn() net.Conn }:
+			inner = wrapper.NetConn()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// UpdateReadLimit changes the read limit of a live conn. On a shared conn it
+// changes the budget shared by both directions.
+func (c *RateLimitedConn) UpdateReadLimit(newLimit int64) {
+	c.readLimiter.SetLimit(newLimit)
+}
+
+// UpdateWriteLimit changes the write limit of a live conn. On a shared conn
+// it changes the budget shared by both directions.
+func (c *RateLimitedConn) UpdateWriteLimit(newLimit int64) {
+	c.writeLimiter.SetLimit(newLimit)
+}
+
+// grant sleeps until up to size bytes may be transferred on limiter and
+// returns how many.
+func (c *RateLimitedConn) grant(limiter *Limiter, size int64) int64 {
+	if c.shared {
+		c.activeTransfers.Add(1)
+		defer c.activeTransfers.Add(-1)
+	}
+
+	iterLimit := limiter.iterLimit()
+	if iterLimit <= 0 {
+		return size
+	}
+
+	allowedBytes := iterLimit
+	if c.shared {
+		if share := iterLimit / c.activeTransfers.Load(); share > 0 {
+			allowedBytes = share
+		}
+	}
+	if size < allowedBytes {
+		allowedBytes = size
+	}
+
+	if sleepTime := limiter.take(allowedBytes, iterLimit); sleepTime > 0 {
+		limiter.sleep(sleepTime, allowedBytes)
+	}
+	return allowedBytes
+}
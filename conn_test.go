@@ -0,0 +1,205 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedConn_SeparateLimits(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB each direction
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	conn := NewRateLimitedConn(local, limit, limit)
+
+	start := time.Now()
+	duplex(t, conn, remote, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedConn_SharedLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB each direction
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	conn := NewSharedRateLimitedConn(local, limit)
+
+	start := time.Now()
+	duplex(t, conn, remote, dataSize)
+	// both directions draw from the same budget
+	assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+}
+
+func TestRateLimitedConn_SharedLimitFairness(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB each direction
+	const limit = dataSize     // one direction alone would take a second
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	conn := NewSharedRateLimitedConn(local, limit)
+
+	var readDone, writeDone time.Duration
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		conn.Write(make([]byte, dataSize))
+		writeDone = time.Since(start)
+	}()
+	go func() {
+		defer wg.Done()
+		io.ReadFull(remote, make([]byte, dataSize))
+	}()
+	go func() {
+		defer wg.Done()
+		remote.Write(make([]byte, dataSize))
+	}()
+	go func() {
+		defer wg.Done()
+		io.ReadFull(conn, make([]byte, dataSize))
+		readDone = time.Since(start)
+	}()
+	wg.Wait()
+
+	// a fair split has both directions finish together, not one after the other
+	diff := (readDone - writeDone).Abs()
+	if diff > 500*time.Millisecond {
+		t.Fatalf("directions finished too far apart: read after %v, write after %v", readDone, writeDone)
+	}
+}
+
+func TestRateLimitedConn_NoLimit(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	conn := NewRateLimitedConn(local, 0, 0)
+
+	start := time.Now()
+	duplex(t, conn, remote, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestRateLimitedConn_UpdateWriteLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB each direction
+	const limit = dataSize     // a second per direction
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	conn := NewRateLimitedConn(local, limit, limit)
+	conn.UpdateWriteLimit(limit / 2) // squeeze uploads only
+
+	var readDone, writeDone time.Duration
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		conn.Write(make([]byte, dataSize))
+		writeDone = time.Since(start)
+	}()
+	go func() {
+		defer wg.Done()
+		io.ReadFull(remote, make([]byte, dataSize))
+	}()
+	go func() {
+		defer wg.Done()
+		remote.Write(make([]byte, dataSize))
+	}()
+	go func() {
+		defer wg.Done()
+		io.ReadFull(conn, make([]byte, dataSize))
+		readDone = time.Since(start)
+	}()
+	wg.Wait()
+
+	assertReadTimes(t, readDone, 1, 1)
+	assertReadTimes(t, writeDone, 2, 2)
+}
+
+// duplex writes and reads dataSize bytes through conn in both directions at
+// once, with remote as the other end.
+func duplex(t *testing.T, conn *RateLimitedConn, remote net.Conn, dataSize int) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		n, err := conn.Write(make([]byte, dataSize))
+		if err == nil && n != dataSize {
+			err = io.ErrShortWrite
+		}
+		errs <- err
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := io.ReadFull(remote, make([]byte, dataSize))
+		errs <- err
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := remote.Write(make([]byte, dataSize))
+		errs <- err
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := io.ReadFull(conn, make([]byte, dataSize))
+		errs <- err
+	}()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestRateLimitedConn_SyscallConn(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	local, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer local.Close()
+
+	var _ syscall.Conn = (*RateLimitedConn)(nil)
+	if _, err := NewRateLimitedConn(local, 0, 0).SyscallConn(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("unexpected error when hidden: %v expected: %v", err, errors.ErrUnsupported)
+	}
+
+	if raw, err := NewRateLimitedConn(local, 0, 0, WithSyscallConn()).SyscallConn(); err != nil || raw == nil {
+		t.Fatalf("unexpected raw conn when passed through: %v error: %v", raw, err)
+	}
+
+	// pipes have no raw conn to pass through
+	pipe, remote := net.Pipe()
+	defer pipe.Close()
+	defer remote.Close()
+	if _, err := NewRateLimitedConn(pipe, 0, 0, WithSyscallConn()).SyscallConn(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("unexpected error without a raw conn: %v expected: %v", err, errors.ErrUnsupported)
+	}
+}
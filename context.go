@@ -0,0 +1,58 @@
+package ratelimitedreader
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type limiterKey struct{}
+
+type requestLimitKey struct{}
+
+// NewContext returns a copy of ctx carrying limiter, so readers created from
+// it along a request's path all draw from the same budget.
+func NewContext(ctx context.Context, limiter *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, limiter)
+}
+
+// FromContext returns the limiter carried by ctx, if any.
+func FromContext(ctx context.Context) (*Limiter, bool) {
+	limiter, ok := ctx.Value(limiterKey{}).(*Limiter)
+	return limiter, ok && limiter != nil
+}
+
+// NewRateLimitedReaderFromContext attaches reader to the limiter carried by
+// ctx, falling back to its own limit when ctx carries none.
+func NewRateLimitedReaderFromContext(ctx context.Context, reader io.Reader, limit int64, opts ...Option) *RateLimitedReader {
+	return NewRateLimitedReadCloserFromContext(ctx, io.NopCloser(reader), limit, opts...)
+}
+
+func NewRateLimitedReadCloserFromContext(ctx context.Context, reader io.ReadCloser, limit int64, opts ...Option) *RateLimitedReader {
+	if limiter, ok := FromContext(ctx); ok {
+		return newRateLimitedReadCloser(reader, limiter, opts...)
+	}
+	return NewRateLimitedReadCloser(reader, limit, opts...)
+}
+
+// WithRequestLimit returns a copy of ctx carrying a limit that overrides the
+// default throttle of ThrottledTransport and ThrottledHandler for requests
+// made with it. A limit of 0 or below bypasses throttling.
+func WithRequestLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, requestLimitKey{}, limit)
+}
+
+func requestLimit(ctx context.Context) (int64, bool) {
+	limit, ok := ctx.Value(requestLimitKey{}).(int64)
+	return limit, ok
+}
+
+// WithOnThrottle calls fn every time a read sleeps to stay under the limit,
+// with the context of the read, see ReadContext, and how long it's set to
+// sleep, so traces can show where a transfer spent its time throttled. It's
+// called from the read, before sleeping.
+func WithOnThrottle(fn func(ctx context.Context, wait time.Duration)) Option {
+	return func(r *RateLimitedReader) {
+		r.onThrottle = fn
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFromContext_Empty(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatalf("expected no limiter in an empty context")
+	}
+}
+
+func TestNewRateLimitedReaderFromContext_SharesLimiter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB per reader
+	const bufferSize = 1024
+	const readersAmount = 2
+	const limit = dataSize // a second per reader
+
+	limiter := NewLimiter(limit)
+	ctx := NewContext(context.Background(), limiter)
+
+	start := time.Now()
+	for i := 0; i < readersAmount; i++ {
+		// the limit passed is ignored in favor of the context's limiter
+		ratelimitedReader := NewRateLimitedReaderFromContext(ctx, bytes.NewReader(make([]byte, dataSize)), 0)
+		if ratelimitedReader.Limiter() != limiter {
+			t.Fatalf("expected reader to attach to the context's limiter")
+		}
+		read(t, ratelimitedReader, bufferSize, dataSize)
+	}
+	assertReadTimes(t, time.Since(start), readersAmount, readersAmount+1)
+}
+
+func TestNewRateLimitedReaderFromContext_Fallback(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReaderFromContext(context.Background(), bytes.NewReader(nil), limit)
+	if ratelimitedReader.Limiter().Limit() != limit {
+		t.Fatalf("unexpected limit: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit)
+	}
+}
+
+func TestRateLimitedReader_ReadContextCanceled(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ratelimitedReader.ReadContext(ctx, make([]byte, limit*10))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v expected: %v", err, context.DeadlineExceeded)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+
+	// the reader outlives the read's context
+	if _, err := ratelimitedReader.Read(make([]byte, 10)); err != nil {
+		t.Fatalf("unexpected error after a canceled read: %v", err)
+	}
+}
+
+type traceKey struct{}
+
+func TestRateLimitedReader_ReadContextHooks(t *testing.T) {
+	const dataSize = 4 * 1024 // 4KB
+	const limit = dataSize * 4
+
+	var throttleTraces, eofTraces []any
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit,
+		WithOnThrottle(func(ctx context.Context, wait time.Duration) {
+			throttleTraces = append(throttleTraces, ctx.Value(traceKey{}))
+		}),
+		WithOnEOFContext(func(ctx context.Context, stats Stats) {
+			eofTraces = append(eofTraces, ctx.Value(traceKey{}))
+		}))
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace")
+	buf := make([]byte, dataSize)
+	for {
+		if _, err := ratelimitedReader.ReadContext(ctx, buf); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(throttleTraces) == 0 {
+		t.Fatalf("expected throttle hooks")
+	}
+	for _, trace := range append(throttleTraces, eofTraces...) {
+		if trace != "trace" {
+			t.Fatalf("unexpected hook context value: %v", trace)
+		}
+	}
+	if len(eofTraces) != 1 {
+		t.Fatalf("unexpected EOF hook calls: %d", len(eofTraces))
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+)
+
+// defaultCopyBufferSize matches io.Copy's.
+const defaultCopyBufferSize = 32 * 1024
+
+// CopyOption configures Copy, CopyN and Proxy.
+type CopyOption func(c *copyConfig)
+
+type copyConfig struct {
+	buf []byte
+}
+
+// WithCopyBufferSize copies through a buffer of size bytes, e.g. an
+// interval's budget, so each read lines up with a paced grant.
+func WithCopyBufferSize(size int) CopyOption {
+	return func(c *copyConfig) {
+		if size > 0 {
+			c.buf = make([]byte, size)
+		}
+	}
+}
+
+// WithCopyBuffer copies through buf, for callers reusing their own buffers.
+// Proxy splits it between its two directions.
+func WithCopyBuffer(buf []byte) CopyOption {
+	return func(c *copyConfig) {
+		if len(buf) > 0 {
+			c.buf = buf
+		}
+	}
+}
+
+func newCopyConfig(opts []CopyOption) *copyConfig {
+	c := &copyConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.buf == nil {
+		c.buf = make([]byte, defaultCopyBufferSize)
+	}
+	return c
+}
+
+// Copy copies from src to dst at limit bytes per second until EOF, like
+// io.Copy. It always copies through its own buffer, never through dst's
+// ReadFrom or src's WriteTo, which would bring buffers of their own. Pacing
+// is on the writing side, so data is passed on as soon as src has any rather
+// than once the buffer fills, which suits interactive streams.
+func Copy(dst io.Writer, src io.Reader, limit int64, opts ...CopyOption) (written int64, err error) {
+	return copyBuffer(NewRateLimitedWriter(dst, limit), src, newCopyConfig(opts).buf)
+}
+
+// CopyN copies n bytes from src to dst at limit bytes per second, like
+// io.CopyN, returning io.EOF if src ends first.
+func CopyN(dst io.Writer, src io.Reader, n, limit int64, opts ...CopyOption) (written int64, err error) {
+	written, err = Copy(dst, io.LimitReader(src, n), limit, opts...)
+	if written < n && err == nil {
+		err = io.EOF
+	}
+	return written, err
+}
+
+// Proxy copies between a and b in both directions at once, each at limit
+// bytes per second, until both reach EOF or either fails. When a direction
+// ends, the side it wrote to has its write half closed if it supports
+// CloseWrite, as TCP conns do. When a direction fails, both sides are closed
+// if they are io.Closers, so the other direction isn't left waiting on a
+// quiet side, and Proxy returns that first error.
+func Proxy(a, b io.ReadWriter, limit int64, opts ...CopyOption) error {
+	buf := newCopyConfig(opts).buf
+	if len(buf) < 2 {
+		buf = make([]byte, 2)
+	}
+	bufs := [2][]byte{buf[:len(buf)/2], buf[len(buf)/2:]}
+
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			for _, side := range []io.ReadWriter{a, b} {
+				if closer, ok := side.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		})
+	}
+	pipe := func(dst, src io.ReadWriter, buf []byte) {
+		defer wg.Done()
+
+		if _, err := copyBuffer(NewRateLimitedWriter(dst, limit), src, buf); err != nil {
+			fail(err)
+			return
+		}
+		if closer, ok := dst.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+	}
+
+	wg.Add(2)
+	go pipe(b, a, bufs[0])
+	go pipe(a, b, bufs[1])
+	wg.Wait()
+
+	return firstErr
+}
+
+// copyBuffer copies through buf, hiding dst's ReadFrom and src's WriteTo.
+func copyBuffer(dst io.Writer, src io.Reader, buf []byte) (int64, error) {
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, buf)
+}
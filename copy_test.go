@@ -0,0 +1,142 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var dst bytes.Buffer
+	start := time.Now()
+	written, err := Copy(&dst, bytes.NewReader(make([]byte, dataSize)), limit)
+	if err != nil || written != dataSize {
+		t.Fatalf("unexpected copy, written: %d error: %v", written, err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestCopy_CopyBuffer(t *testing.T) {
+	const dataSize = 1024
+	const bufferSize = 100
+
+	buf := make([]byte, bufferSize)
+	dst := &writeSizeRecorder{}
+	if _, err := Copy(dst, bytes.NewReader(make([]byte, dataSize)), 0, WithCopyBuffer(buf)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.maxWrite != bufferSize {
+		t.Fatalf("unexpected largest write: %d expected: %d", dst.maxWrite, bufferSize)
+	}
+
+	dst = &writeSizeRecorder{}
+	if _, err := Copy(dst, bytes.NewReader(make([]byte, dataSize)), 0, WithCopyBufferSize(bufferSize*2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.maxWrite != bufferSize*2 {
+		t.Fatalf("unexpected largest write: %d expected: %d", dst.maxWrite, bufferSize*2)
+	}
+}
+
+func TestCopyN(t *testing.T) {
+	const dataSize = 1024
+
+	var dst bytes.Buffer
+	written, err := CopyN(&dst, bytes.NewReader(make([]byte, dataSize)), dataSize/2, 0)
+	if err != nil || written != dataSize/2 {
+		t.Fatalf("unexpected copy, written: %d error: %v", written, err)
+	}
+
+	written, err = CopyN(&dst, bytes.NewReader(make([]byte, dataSize)), dataSize*2, 0)
+	if err != io.EOF || written != dataSize {
+		t.Fatalf("unexpected short copy, written: %d error: %v expected: %v", written, err, io.EOF)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB each direction
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	clientLocal, clientRemote := net.Pipe()
+	serverLocal, serverRemote := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Proxy(clientRemote, serverLocal, limit)
+	}()
+
+	start := time.Now()
+	errs := make(chan error, 4)
+	go func() {
+		_, err := clientLocal.Write(make([]byte, dataSize))
+		errs <- err
+	}()
+	go func() {
+		_, err := serverRemote.Write(make([]byte, dataSize))
+		errs <- err
+	}()
+	go func() {
+		_, err := io.ReadFull(serverRemote, make([]byte, dataSize))
+		errs <- err
+	}()
+	go func() {
+		_, err := io.ReadFull(clientLocal, make([]byte, dataSize))
+		errs <- err
+	}()
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// both directions run at once
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	clientLocal.Close()
+	serverRemote.Close()
+	<-done
+}
+
+func TestProxy_FailureEndsIdleDirection(t *testing.T) {
+	const limit = 1024
+
+	// b's peer never writes, so only closing b ends the b to a direction
+	failing := struct {
+		io.Reader
+		io.Writer
+	}{iotest.ErrReader(errTransient), io.Discard}
+	b, peer := net.Pipe()
+	defer peer.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Proxy(failing, b, limit)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errTransient) {
+			t.Fatalf("unexpected error: %v expected: %v", err, errTransient)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("proxy kept waiting on the idle direction after a failure")
+	}
+}
+
+// writeSizeRecorder records the largest write it was given.
+type writeSizeRecorder struct {
+	maxWrite int
+}
+
+func (w *writeSizeRecorder) Write(p []byte) (int, error) {
+	w.maxWrite = max(w.maxWrite, len(p))
+	return len(p), nil
+}
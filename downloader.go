@@ -0,0 +1,175 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+)
+
+// defaultDownloadChunkSize is the size of the ranges a Downloader fetches,
+// see WithDownloadChunkSize.
+const defaultDownloadChunkSize = 256 << 10
+
+// ReaderAtFunc adapts a range fetch, such as an HTTP range request, to an
+// io.ReaderAt for a Downloader.
+type ReaderAtFunc func(p []byte, off int64) (int, error)
+
+func (f ReaderAtFunc) ReadAt(p []byte, off int64) (int, error) {
+	return f(p, off)
+}
+
+// DownloaderOption configures a Downloader at construction.
+type DownloaderOption func(d *Downloader)
+
+// WithDownloadChunkSize sets the size of the ranges workers fetch.
+func WithDownloadChunkSize(size int64) DownloaderOption {
+	return func(d *Downloader) {
+		if size > 0 {
+			d.chunkSize = size
+		}
+	}
+}
+
+// Downloader fetches size bytes of src in ranges from concurrency workers,
+// all drawing from limiter so together they never exceed its limit, and
+// reads them back as one ordered stream. At most concurrency ranges are
+// fetched ahead of the reader. Workers start on the first Read; Close stops
+// them.
+type Downloader struct {
+	src         io.ReaderAt
+	size        int64
+	concurrency int
+	limiter     *Limiter
+	chunkSize   int64
+
+	start   sync.Once
+	stop    chan struct{}
+	stopped sync.Once
+	// chunks are the ranges in stream order, filled by workers
+	chunks  chan *downloadChunk
+	current *downloadChunk
+	err     error
+}
+
+// downloadChunk is a range fetched by a worker, done once filled.
+type downloadChunk struct {
+	off  int64
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+func NewDownloader(src io.ReaderAt, size int64, concurrency int, limiter *Limiter, opts ...DownloaderOption) *Downloader {
+	d := &Downloader{
+		src:         src,
+		size:        size,
+		concurrency: max(concurrency, 1),
+		limiter:     limiter,
+		chunkSize:   defaultDownloadChunkSize,
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.chunks = make(chan *downloadChunk, d.concurrency)
+	return d
+}
+
+func (d *Downloader) Read(p []byte) (n int, err error) {
+	d.start.Do(d.run)
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	for n < len(p) {
+		if d.current == nil || len(d.current.data) == 0 {
+			chunk, ok := <-d.chunks
+			if !ok {
+				d.err = io.EOF
+				if d.closed() {
+					d.err = ErrClosed
+				}
+				break
+			}
+			<-chunk.done
+			if chunk.err != nil {
+				d.err = chunk.err
+				break
+			}
+			d.current = chunk
+		}
+
+		m := copy(p[n:], d.current.data)
+		d.current.data = d.current.data[m:]
+		n += m
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+func (d *Downloader) Close() error {
+	d.stopped.Do(func() { close(d.stop) })
+	return nil
+}
+
+func (d *Downloader) closed() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// run starts the workers and queues the ranges for them in stream order.
+func (d *Downloader) run() {
+	work := make(chan *downloadChunk)
+	for range d.concurrency {
+		go d.work(work)
+	}
+
+	go func() {
+		defer close(work)
+		defer close(d.chunks)
+		for off := int64(0); off < d.size; off += d.chunkSize {
+			chunk := &downloadChunk{
+				off:  off,
+				data: make([]byte, min(d.chunkSize, d.size-off)),
+				done: make(chan struct{}),
+			}
+			select {
+			case d.chunks <- chunk:
+			case <-d.stop:
+				return
+			}
+			select {
+			case work <- chunk:
+			case <-d.stop:
+				chunk.err = ErrClosed
+				close(chunk.done)
+				return
+			}
+		}
+	}()
+}
+
+// work fetches chunks at the limiter's pace until there are no more.
+func (d *Downloader) work(work <-chan *downloadChunk) {
+	r := newRateLimitedReadCloser(nil, d.limiter, WithStopChannel(d.stop))
+	paced := pacedReaderAt{r: r, readerAt: d.src}
+	for chunk := range work {
+		n, err := paced.ReadAt(chunk.data, chunk.off)
+		if err == io.EOF && n == len(chunk.data) {
+			err = nil
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		chunk.data, chunk.err = chunk.data[:n], err
+		close(chunk.done)
+	}
+}
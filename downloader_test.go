@@ -0,0 +1,90 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloader(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+	const concurrency = 4
+
+	data := make([]byte, dataSize)
+	rand.Read(data)
+
+	// a source slow to answer, counting the workers reading at once
+	var active, peak atomic.Int64
+	src := ReaderAtFunc(func(p []byte, off int64) (int, error) {
+		peak.Store(max(peak.Load(), active.Add(1)))
+		defer active.Add(-1)
+		time.Sleep(100 * time.Millisecond)
+		return bytes.NewReader(data).ReadAt(p, off)
+	})
+
+	downloader := NewDownloader(src, dataSize, concurrency, NewLimiter(limit), WithDownloadChunkSize(dataSize/16))
+	defer downloader.Close()
+
+	start := time.Now()
+	downloaded, err := io.ReadAll(downloader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	if !bytes.Equal(downloaded, data) {
+		t.Fatalf("downloaded data out of order")
+	}
+	if peak.Load() < 2 {
+		t.Fatalf("expected concurrent range reads, peak: %d", peak.Load())
+	}
+}
+
+func TestDownloader_SourceError(t *testing.T) {
+	const dataSize = 4 * 1024
+	errSource := errors.New("source failed")
+
+	src := ReaderAtFunc(func(p []byte, off int64) (int, error) {
+		if off > 0 {
+			return 0, errSource
+		}
+		clear(p)
+		return len(p), nil
+	})
+
+	downloader := NewDownloader(src, dataSize, 2, NewLimiter(0), WithDownloadChunkSize(dataSize/4))
+	defer downloader.Close()
+
+	downloaded, err := io.ReadAll(downloader)
+	if !errors.Is(err, errSource) {
+		t.Fatalf("unexpected error: %v expected: %v", err, errSource)
+	}
+	if len(downloaded) != dataSize/4 {
+		t.Fatalf("unexpected downloaded size before the error: %d expected: %d", len(downloaded), dataSize/4)
+	}
+}
+
+func TestDownloader_Close(t *testing.T) {
+	const dataSize = 40 * 1024
+	const limit = dataSize / 8
+
+	downloader := NewDownloader(bytes.NewReader(make([]byte, dataSize)), dataSize, 2, NewLimiter(limit))
+	time.AfterFunc(200*time.Millisecond, func() { downloader.Close() })
+
+	start := time.Now()
+	_, err := io.ReadAll(downloader)
+	if err != ErrClosed {
+		t.Fatalf("unexpected error after close: %v expected: %v", err, ErrClosed)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("close noticed too late, elapsed time: %v", elapsed)
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimitedreader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Sentinel errors returned by the package, to be matched with errors.Is.
+var (
+	// ErrInvalidLimit is returned for a limit or configuration that can't be
+	// paced.
+	ErrInvalidLimit = errors.New("rate-limited-reader: invalid limit")
+
+	// ErrNilReader is returned when constructing a reader around nil.
+	ErrNilReader = errors.New("rate-limited-reader: nil reader")
+
+	// ErrClosed is returned by reads on a closed reader.
+	ErrClosed = errors.New("rate-limited-reader: read on closed reader")
+
+	// ErrQuotaExceeded is returned once a transfer used up its quota.
+	ErrQuotaExceeded = errors.New("rate-limited-reader: quota exceeded")
+
+	// ErrPaused is returned by non-blocking calls while reading is paused.
+	ErrPaused = errors.New("rate-limited-reader: paused")
+
+	// ErrWouldBlock is returned by non-blocking calls when the limiter has no
+	// budget available right now.
+	ErrWouldBlock = errors.New("rate-limited-reader: would block")
+
+	// ErrUnknownAlgorithm is returned for an algorithm name that was never
+	// registered.
+	ErrUnknownAlgorithm = errors.New("rate-limited-reader: unknown algorithm")
+
+	// ErrMalformedConfig is returned when decoding a config that isn't a
+	// valid config.proto message.
+	ErrMalformedConfig = errors.New("rate-limited-reader: malformed config")
+
+	// ErrOverbooked is returned when booking bandwidth beyond what is free.
+	ErrOverbooked = errors.New("rate-limited-reader: bandwidth overbooked")
+)
+
+// TransferError wraps an error from the underlying reader with how far the
+// transfer had gotten when it failed. Get it with errors.As; errors.Is still
+// matches the underlying error.
+type TransferError struct {
+	Err error
+
+	// BytesRead is the total read through the reader and Elapsed the time
+	// since its first read.
+	BytesRead int64
+	Elapsed   time.Duration
+	Limit     int64
+}
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("rate-limited-reader: read failed after %d bytes in %v (limit %d): %v", e.BytesRead, e.Elapsed, e.Limit, e.Err)
+}
+
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
+
+// IsTimeout reports whether err is a timeout or a temporary error, such as a
+// net.Error timing out on a deadline, which a retry may get past.
+func IsTimeout(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
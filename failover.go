@@ -0,0 +1,90 @@
+package ratelimitedreader
+
+import (
+	"errors"
+	"io"
+)
+
+// SourceFunc opens a source for a failover reader positioned at offset, the
+// bytes already read from the sources before it, such as a mirror fetched
+// with an HTTP range request from offset on.
+type SourceFunc func(offset int64) (io.ReadCloser, error)
+
+// NewFailover returns a reader over sources in order, which moves on to the
+// next source whenever opening or reading the current one fails, opening it
+// where the last one left off. All sources share the reader's one limit and
+// Stats, so mirror-based downloads stay under one cap however often they
+// switch. io.EOF from a source ends the stream; once every source failed,
+// reads fail with all of their errors joined. Sources are opened on first
+// Read, and Close closes the current one.
+func NewFailover(sources []SourceFunc, limit int64, opts ...Option) *RateLimitedReader {
+	return NewRateLimitedReadCloser(&failoverReader{sources: sources}, limit, opts...)
+}
+
+// failoverReader reads sources in turn, from the offset reached so far.
+type failoverReader struct {
+	sources []SourceFunc
+	// next is the index of the next source to open, current the open one
+	next    int
+	current io.ReadCloser
+	offset  int64
+	// errs are the errors sources failed with, err set once all did
+	errs []error
+	err  error
+}
+
+func (f *failoverReader) Read(p []byte) (int, error) {
+	for {
+		if f.err != nil {
+			return 0, f.err
+		}
+		if f.current == nil && !f.open() {
+			continue
+		}
+
+		n, err := f.current.Read(p)
+		f.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+
+		f.fail(err)
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// open opens the next source, reporting whether it did.
+func (f *failoverReader) open() bool {
+	if f.next >= len(f.sources) {
+		f.err = errors.Join(f.errs...)
+		if f.err == nil {
+			f.err = io.EOF
+		}
+		return false
+	}
+
+	source, err := f.sources[f.next](f.offset)
+	f.next++
+	if err != nil {
+		f.errs = append(f.errs, err)
+		return false
+	}
+	f.current = source
+	return true
+}
+
+// fail drops the current source after it failed with err.
+func (f *failoverReader) fail(err error) {
+	f.errs = append(f.errs, err)
+	f.current.Close()
+	f.current = nil
+}
+
+func (f *failoverReader) Close() error {
+	if f.current == nil {
+		return nil
+	}
+	return f.current.Close()
+}
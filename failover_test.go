@@ -0,0 +1,72 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestNewFailover(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+	const failAfter = dataSize / 4
+
+	data := make([]byte, dataSize)
+	rand.Read(data)
+	errMirror := errors.New("mirror down")
+
+	var offsets []int64
+	sources := []SourceFunc{
+		// fails a quarter of the way in
+		func(offset int64) (io.ReadCloser, error) {
+			offsets = append(offsets, offset)
+			return io.NopCloser(io.MultiReader(bytes.NewReader(data[offset:failAfter]), iotest.ErrReader(errMirror))), nil
+		},
+		// fails to open
+		func(offset int64) (io.ReadCloser, error) {
+			offsets = append(offsets, offset)
+			return nil, errMirror
+		},
+		func(offset int64) (io.ReadCloser, error) {
+			offsets = append(offsets, offset)
+			return io.NopCloser(bytes.NewReader(data[offset:])), nil
+		},
+	}
+
+	ratelimitedReader := NewFailover(sources, limit)
+	var got bytes.Buffer
+	start := time.Now()
+	if _, err := io.CopyBuffer(&got, ratelimitedReader, make([]byte, bufferSize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// one budget across the sources
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("unexpected data across sources")
+	}
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != failAfter || offsets[2] != failAfter {
+		t.Fatalf("unexpected source offsets: %v expected: [0 %d %d]", offsets, failAfter, failAfter)
+	}
+}
+
+func TestNewFailover_AllFail(t *testing.T) {
+	errOpen := errors.New("can't open")
+	errRead := errors.New("can't read")
+
+	ratelimitedReader := NewFailover([]SourceFunc{
+		func(int64) (io.ReadCloser, error) { return nil, errOpen },
+		func(int64) (io.ReadCloser, error) { return io.NopCloser(iotest.ErrReader(errRead)), nil },
+	}, 1024)
+
+	_, err := ratelimitedReader.Read(make([]byte, 10))
+	if !errors.Is(err, errOpen) || !errors.Is(err, errRead) {
+		t.Fatalf("unexpected error: %v expected both sources' errors", err)
+	}
+}
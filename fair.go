@@ -0,0 +1,78 @@
+package ratelimitedreader
+
+import "sync"
+
+// WithFairReads makes the reader safe to share between goroutines, serving
+// their reads in turns: callers are queued first come first served, and each
+// turn reads at most one interval's budget, so a caller with a large buffer
+// can't hold the budget while others wait. A caller wanting more queues up
+// again, as io.Reader callers do on short reads. TryRead doesn't queue: it
+// returns ErrWouldBlock while another caller has the turn.
+//
+// Without it a reader is meant for one goroutine at a time, like most
+// io.Readers.
+func WithFairReads() Option {
+	return func(r *RateLimitedReader) {
+		r.fair = &fairQueue{}
+	}
+}
+
+// fairQueue hands out turns in the order they were asked for.
+type fairQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	waiters []chan struct{}
+}
+
+// acquire waits for the caller's turn.
+func (q *fairQueue) acquire() {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return
+	}
+
+	turn := make(chan struct{})
+	q.waiters = append(q.waiters, turn)
+	q.mu.Unlock()
+	<-turn
+}
+
+// tryAcquire takes the turn if no one has it, reporting whether it did.
+func (q *fairQueue) tryAcquire() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.busy {
+		return false
+	}
+	q.busy = true
+	return true
+}
+
+// release hands the turn to the longest waiting caller.
+func (q *fairQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiters) == 0 {
+		q.busy = false
+		return
+	}
+
+	next := q.waiters[0]
+	q.waiters[0] = nil
+	q.waiters = q.waiters[1:]
+	close(next)
+}
+
+// fairShare caps p to one interval's budget, a caller's share of a turn.
+func (r *RateLimitedReader) fairShare(p []byte) []byte {
+	if limit := r.paceLimit(); limit > 0 {
+		if grant := r.grantSize(limit); grant < int64(len(p)) {
+			return p[:grant]
+		}
+	}
+	return p
+}
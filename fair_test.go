@@ -0,0 +1,68 @@
+package ratelimitedreader
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_FairReads(t *testing.T) {
+	const limit = 30 * 1024
+	const bufferSize = limit // a whole second per read without turns
+	const callersAmount = 3
+	const duration = 1500 * time.Millisecond
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithFairReads())
+
+	var wg sync.WaitGroup
+	totals := make([]int, callersAmount)
+	for i := range callersAmount {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, bufferSize)
+			for start := time.Now(); time.Since(start) < duration; {
+				n, _ := ratelimitedReader.Read(buf)
+				totals[i] += n
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int
+	for _, callerTotal := range totals {
+		total += callerTotal
+	}
+	for i, callerTotal := range totals {
+		if share := total / callersAmount; callerTotal < share*3/4 || callerTotal > share*5/4 {
+			t.Fatalf("unfair share for caller %d: %d of %d, totals: %v", i, callerTotal, total, totals)
+		}
+	}
+}
+
+func TestFairQueue_Order(t *testing.T) {
+	const waitersAmount = 4
+
+	var q fairQueue
+	q.acquire()
+	if q.tryAcquire() {
+		t.Fatalf("expected tryAcquire to fail while the turn is taken")
+	}
+
+	order := make(chan int, waitersAmount)
+	for i := range waitersAmount {
+		go func() {
+			q.acquire()
+			order <- i
+			q.release()
+		}()
+		time.Sleep(10 * time.Millisecond) // queue in order
+	}
+	q.release()
+
+	for expected := range waitersAmount {
+		if i := <-order; i != expected {
+			t.Fatalf("unexpected turn order: %d expected: %d", i, expected)
+		}
+	}
+}
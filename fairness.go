@@ -0,0 +1,152 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxFairnessWindow is the longest window FairnessReport measures
+// consumption over; longer windows are capped to it.
+const MaxFairnessWindow = minuteBuckets * minuteBucket
+
+// FairnessReport compares, per reader and per tenant, the share of the
+// managed limits allocated against the share of the managed traffic
+// consumed over Window.
+type FairnessReport struct {
+	// Window is the window consumption was measured over: the window asked
+	// for, rounded up to whole seconds, at least a second and at most
+	// MaxFairnessWindow.
+	Window time.Duration `json:"window"`
+
+	// Readers has a line per registered reader, sorted by name.
+	Readers []Fairness `json:"readers"`
+
+	// Tenants has a line per tenant, summing its readers, sorted by tenant.
+	// Its lines' Name is the tenant.
+	Tenants []Fairness `json:"tenants"`
+}
+
+// Fairness is a line of a FairnessReport: the share of the managed limits
+// allocated to a reader or tenant against the share of the managed traffic
+// it consumed.
+type Fairness struct {
+	// Name is the name the reader was registered under, or the tenant.
+	Name string `json:"name"`
+
+	// Tenant is the tenant the reader belongs to, see SetTenant.
+	Tenant string `json:"tenant"`
+
+	// Limit is the limit allocated, counting a limiter shared by several
+	// readers once. 0 if unlimited.
+	Limit int64 `json:"limit"`
+
+	// Throughput is the bytes per second delivered over the window.
+	Throughput int64 `json:"throughput"`
+
+	// AllocatedShare is the fraction of the limits of all the reported
+	// readers allocated, readers sharing a limiter splitting its share
+	// evenly. 0 for unlimited readers.
+	AllocatedShare float64 `json:"allocated_share"`
+
+	// ConsumedShare is Throughput as a fraction of the throughput of all
+	// the reported readers, 0 if none delivered anything.
+	ConsumedShare float64 `json:"consumed_share"`
+}
+
+// SetTenant files the reader under name with tenant in FairnessReport, so
+// the readers of one customer are compared against the others as a whole.
+// Readers with no tenant set are their own tenant, under their name.
+func (m *Manager) SetTenant(name, tenant string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tenants[name] = tenant
+}
+
+// FairnessReport summarizes the share of the limits allocated to each
+// registered reader and tenant against the share of the traffic it consumed
+// over window, rounded up to whole seconds and at most MaxFairnessWindow,
+// so operators of shared caps can check that no tenant takes more than its
+// part. A tenant consuming well
+// below its allocation is starved or idle; one above it is taking up slack
+// the others left.
+func (m *Manager) FairnessReport(window time.Duration) FairnessReport {
+	window = measuredWindow(window)
+	now := time.Now()
+
+	type entry struct {
+		Fairness
+		limiter *Limiter
+	}
+
+	m.mu.RLock()
+	entries := make([]entry, 0, len(m.readers))
+	for name, reader := range m.readers {
+		tenant, ok := m.tenants[name]
+		if !ok {
+			tenant = name
+		}
+		entries = append(entries, entry{
+			Fairness: Fairness{
+				Name:       name,
+				Tenant:     tenant,
+				Limit:      max(reader.Limiter().Limit(), 0),
+				Throughput: reader.throughput.windowRate(now, window),
+			},
+			limiter: reader.Limiter(),
+		})
+	}
+	m.mu.RUnlock()
+
+	// a limiter shared by several readers is allocated once, split evenly
+	sharing := make(map[*Limiter]int)
+	var limits, throughputs int64
+	for _, e := range entries {
+		if sharing[e.limiter] == 0 {
+			limits += e.Limit
+		}
+		sharing[e.limiter]++
+		throughputs += e.Throughput
+	}
+
+	report := FairnessReport{Window: window, Readers: make([]Fairness, 0, len(entries))}
+	tenants := make(map[string]*Fairness)
+	tenantLimiters := make(map[string]map[*Limiter]bool)
+	for _, e := range entries {
+		f := e.Fairness
+		if limits > 0 {
+			f.AllocatedShare = float64(f.Limit) / float64(limits) / float64(sharing[e.limiter])
+		}
+		if throughputs > 0 {
+			f.ConsumedShare = float64(f.Throughput) / float64(throughputs)
+		}
+		report.Readers = append(report.Readers, f)
+
+		t, ok := tenants[f.Tenant]
+		if !ok {
+			t = &Fairness{Name: f.Tenant, Tenant: f.Tenant}
+			tenants[f.Tenant] = t
+			tenantLimiters[f.Tenant] = make(map[*Limiter]bool)
+		}
+		if !tenantLimiters[f.Tenant][e.limiter] {
+			tenantLimiters[f.Tenant][e.limiter] = true
+			t.Limit += f.Limit
+		}
+		t.Throughput += f.Throughput
+		t.AllocatedShare += f.AllocatedShare
+		t.ConsumedShare += f.ConsumedShare
+	}
+	for _, t := range tenants {
+		report.Tenants = append(report.Tenants, *t)
+	}
+
+	byName := func(a, b Fairness) int {
+		return strings.Compare(a.Name, b.Name)
+	}
+	slices.SortFunc(report.Readers, byName)
+	slices.SortFunc(report.Tenants, byName)
+	return report
+}
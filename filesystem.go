@@ -0,0 +1,58 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import "net/http"
+
+// ThrottledFileSystem returns a file system whose files read at no more than
+// perFileLimit each and limit all together, for serving static files with a
+// bandwidth cap through a stock http.FileServer. Files keep their Seek, Stat
+// and Readdir, and seeking moves no data so it costs no budget. A limit of 0
+// or below leaves that side unlimited.
+func ThrottledFileSystem(fs http.FileSystem, limit, perFileLimit int64) http.FileSystem {
+	return &throttledFileSystem{
+		fs:           fs,
+		limiter:      NewLimiter(limit),
+		perFileLimit: perFileLimit,
+	}
+}
+
+type throttledFileSystem struct {
+	fs           http.FileSystem
+	limiter      *Limiter
+	perFileLimit int64
+}
+
+func (t *throttledFileSystem) Open(name string) (http.File, error) {
+	file, err := t.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// drawing from the shared limiter under the file's own
+	shared := newRateLimitedReadCloser(file, t.limiter)
+	return throttledFile{
+		File:   file,
+		shared: shared,
+		reader: NewRateLimitedReadCloser(shared, t.perFileLimit),
+	}, nil
+}
+
+// throttledFile reads through reader, which reads through shared, everything
+// else goes to File.
+type throttledFile struct {
+	http.File
+	shared *RateLimitedReader
+	reader *RateLimitedReader
+}
+
+func (f throttledFile) Read(p []byte) (int, error) {
+	return f.reader.Read(p)
+}
+
+func (f throttledFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.File.Seek(offset, whence)
+	f.shared.seeked()
+	f.reader.seeked()
+	return n, err
+}
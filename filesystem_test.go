@@ -0,0 +1,96 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottledFileSystem_PerFileLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const perFileLimit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := httptest.NewServer(http.FileServer(ThrottledFileSystem(fileDir(t, 2, dataSize), 0, perFileLimit)))
+	defer server.Close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, http.DefaultClient, server.URL+"/"+fileName(i), dataSize)
+		}()
+	}
+	wg.Wait()
+	// files don't share their limit
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestThrottledFileSystem_SharedLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := httptest.NewServer(http.FileServer(ThrottledFileSystem(fileDir(t, 2, dataSize), limit, 0)))
+	defer server.Close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, http.DefaultClient, server.URL+"/"+fileName(i), dataSize)
+		}()
+	}
+	wg.Wait()
+	assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+}
+
+func TestThrottledFileSystem_Readdir(t *testing.T) {
+	const dataSize = 1024
+
+	server := httptest.NewServer(http.FileServer(ThrottledFileSystem(fileDir(t, 2, dataSize), dataSize, dataSize)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	listing, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if !strings.Contains(string(listing), fileName(i)) {
+			t.Fatalf("expected %s in the directory listing: %s", fileName(i), listing)
+		}
+	}
+}
+
+// fileDir returns a directory of amount files of dataSize bytes each.
+func fileDir(t *testing.T, amount, dataSize int) http.Dir {
+	dir := t.TempDir()
+	for i := 0; i < amount; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fileName(i)), make([]byte, dataSize), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return http.Dir(dir)
+}
+
+func fileName(i int) string {
+	return "file" + string(rune('a'+i))
+}
@@ -0,0 +1,57 @@
+package ratelimitedreader
+
+import "time"
+
+// grace lets a stream exceed its limit by a bounded allowance per period.
+type grace struct {
+	excess    float64
+	allowance time.Duration
+	period    time.Duration
+
+	// start is when the current period started, zero before the first read,
+	// and used how many bytes above the limit it has read so far
+	start time.Time
+	used  int64
+	// boosted is set while reads are paced at the hard limit
+	boosted bool
+}
+
+// WithGraceBurst makes the reader's limit a soft one, which reads may exceed
+// by excess, e.g. 0.2 for 20%, for up to allowance per period, e.g. 5s a
+// minute, before the hard cap clamps down to the limit again, as ISPs' burst
+// policies do. The allowance is counted in the bytes read above the limit,
+// so bursts shorter or smaller than excess last longer. Periods start with
+// the first read.
+func WithGraceBurst(excess float64, allowance, period time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		if excess <= 0 || allowance <= 0 || period <= 0 {
+			return
+		}
+		r.grace = &grace{excess: excess, allowance: allowance, period: period}
+	}
+}
+
+// iterLimit returns iterLimit, the soft limit's budget per interval, raised
+// to the hard limit while the period's allowance lasts. limit is the soft
+// limit per second.
+func (g *grace) iterLimit(now time.Time, iterLimit, limit int64) int64 {
+	if g.start.IsZero() || !now.Before(g.start.Add(g.period)) {
+		g.start = now
+		g.used = 0
+	}
+
+	allowance := int64(float64(limit) * g.excess * g.allowance.Seconds())
+	g.boosted = g.used < allowance
+	if !g.boosted {
+		return iterLimit
+	}
+	return int64(float64(iterLimit) * (1 + g.excess))
+}
+
+// use counts n bytes read, the part of which above the limit uses up the
+// allowance while boosted.
+func (g *grace) use(n int) {
+	if g.boosted {
+		g.used += int64(float64(n) * g.excess / (1 + g.excess))
+	}
+}
@@ -0,0 +1,35 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_GraceBurst(t *testing.T) {
+	const limit = 10 * 1024 // soft limit
+	const dataSize = limit * 3
+	const bufferSize = 1024
+
+	// twice the limit for a second's worth of excess, then the limit: 20KB
+	// in the first second and 10KB in the next
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithGraceBurst(1, time.Second, time.Minute))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
+
+func TestRateLimitedReader_GraceBurstUsedUp(t *testing.T) {
+	const limit = 10 * 1024 // soft limit
+	const dataSize = limit * 2
+	const bufferSize = 1024
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize*2)), limit, WithGraceBurst(1, time.Second, time.Minute))
+	read(t, ratelimitedReader, bufferSize, dataSize)
+
+	// the period's allowance is used up, so the rest is read at the limit
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
@@ -0,0 +1,102 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Group paces readers per key, such as a user or a tenant: readers wrapped
+// under the same key share that key's budget of limit bytes per second. Keys
+// idle for ttl, with nothing read under them, are evicted, so groups keyed by
+// many short-lived users stay small. Eviction is done on Wrap, without a
+// goroutine of its own.
+type Group[K comparable] struct {
+	limit int64
+	ttl   time.Duration
+	opts  []Option
+
+	mu        sync.Mutex
+	buckets   map[K]*groupBucket
+	lastSweep time.Time
+}
+
+// groupBucket is a key's budget, and when it was last handed out.
+type groupBucket struct {
+	limiter *Limiter
+	wrapped time.Time
+}
+
+// NewGroup returns a group pacing each key at limit, with readers wrapped
+// with opts.
+func NewGroup[K comparable](limit int64, ttl time.Duration, opts ...Option) *Group[K] {
+	return &Group[K]{
+		limit:   limit,
+		ttl:     ttl,
+		opts:    opts,
+		buckets: make(map[K]*groupBucket),
+	}
+}
+
+// Wrap returns a reader drawing from key's budget. A reader left idle past
+// the ttl and read again afterwards keeps its evicted budget, apart from
+// readers wrapped under key since.
+func (g *Group[K]) Wrap(key K, reader io.Reader) *RateLimitedReader {
+	return NewReaderWithLimiter(reader, g.Limiter(key), g.opts...)
+}
+
+// WrapReadCloser is Wrap for an io.ReadCloser.
+func (g *Group[K]) WrapReadCloser(key K, reader io.ReadCloser) *RateLimitedReader {
+	return NewReadCloserWithLimiter(reader, g.Limiter(key), g.opts...)
+}
+
+// Limiter returns key's budget, creating it if needed, for pacing writers
+// and conns along with the key's readers.
+func (g *Group[K]) Limiter(key K) *Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+
+	bucket, ok := g.buckets[key]
+	if !ok {
+		bucket = &groupBucket{limiter: NewLimiter(g.limit)}
+		g.buckets[key] = bucket
+	}
+	bucket.wrapped = now
+	return bucket.limiter
+}
+
+// UpdateLimit changes the limit of every key, including live readers'.
+func (g *Group[K]) UpdateLimit(newLimit int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.limit = newLimit
+	for _, bucket := range g.buckets {
+		bucket.limiter.SetLimit(newLimit)
+	}
+}
+
+// Len returns how many keys the group holds a budget for.
+func (g *Group[K]) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return len(g.buckets)
+}
+
+// sweep evicts the keys idle for ttl, at most once every ttl. Must hold g.mu.
+func (g *Group[K]) sweep(now time.Time) {
+	if g.ttl <= 0 || now.Sub(g.lastSweep) < g.ttl {
+		return
+	}
+	g.lastSweep = now
+
+	for key, bucket := range g.buckets {
+		if now.Sub(bucket.wrapped) >= g.ttl && now.Sub(bucket.limiter.lastActive()) >= g.ttl {
+			delete(g.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroup_SharesBudgetPerKey(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB per reader
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	group := NewGroup[string](limit, time.Minute)
+
+	// two readers of a, sharing its budget, and one of b on its own
+	start := time.Now()
+	var wg sync.WaitGroup
+	elapsed := make(map[string]time.Duration)
+	var mu sync.Mutex
+	for _, key := range []string{"a", "a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(t, group.Wrap(key, bytes.NewReader(make([]byte, dataSize))), bufferSize, dataSize)
+
+			mu.Lock()
+			defer mu.Unlock()
+			elapsed[key] = max(elapsed[key], time.Since(start))
+		}()
+	}
+	wg.Wait()
+
+	assertReadTimes(t, elapsed["a"], partsAmount*2, partsAmount*2+1)
+	assertReadTimes(t, elapsed["b"], partsAmount, partsAmount+1)
+	if group.Len() != 2 {
+		t.Fatalf("unexpected keys: %d expected: 2", group.Len())
+	}
+}
+
+func TestGroup_EvictsIdleKeys(t *testing.T) {
+	const limit = 10 * 1024
+	const ttl = 100 * time.Millisecond
+
+	group := NewGroup[int](limit, ttl)
+	read(t, group.Wrap(1, bytes.NewReader(make([]byte, limit/10))), limit/10, limit/10)
+
+	time.Sleep(ttl * 3)
+	group.Wrap(2, bytes.NewReader(nil))
+	if group.Len() != 1 {
+		t.Fatalf("unexpected keys after the ttl: %d expected: 1", group.Len())
+	}
+
+	// keys read from within the ttl stay
+	read(t, group.Wrap(2, bytes.NewReader(make([]byte, limit))), limit/10, limit)
+	group.Wrap(3, bytes.NewReader(nil))
+	if group.Len() != 2 {
+		t.Fatalf("unexpected keys within the ttl: %d expected: 2", group.Len())
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// ThrottledHandler rate limits the request bodies handler reads to limit per
+// request. Requests whose context carries WithRequestLimit use that limit
+// instead, and ones carrying a Limiter from NewContext draw from it.
+func ThrottledHandler(handler http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			if requestLimit, ok := requestLimit(r.Context()); ok {
+				r.Body = NewRateLimitedReadCloser(r.Body, requestLimit)
+			} else {
+				r.Body = NewRateLimitedReadCloserFromContext(r.Context(), r.Body, limit)
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// GroupHandler rate limits the request bodies handler reads per key, as
+// picked from each request by key, such as a user ID or the client address,
+// so requests of the same key share its budget in group.
+func GroupHandler[K comparable](handler http.Handler, group *Group[K], key func(r *http.Request) K) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = group.WrapReadCloser(key(r), r.Body)
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ServeContent is http.ServeContent sending content at no more than limit
+// bytes per second. Range and If-Range requests are handled exactly as
+// http.ServeContent does: seeking to a range costs no budget, and only the
+// bytes of the ranges sent are paced.
+func ServeContent(w http.ResponseWriter, req *http.Request, name string, modtime time.Time, content io.ReadSeeker, limit int64) {
+	http.ServeContent(w, req, name, modtime, Wrap(content, limit).(io.ReadSeeker))
+}
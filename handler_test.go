@@ -0,0 +1,150 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestThrottledHandler_Limit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := httptest.NewServer(ThrottledHandler(drainBody(t, dataSize), limit))
+	defer server.Close()
+
+	start := time.Now()
+	post(t, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestThrottledHandler_RequestLimitBypass(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const limit = 1024          // slow default
+
+	handler := ThrottledHandler(drainBody(t, dataSize), limit)
+	bypass := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithRequestLimit(r.Context(), 0)))
+	})
+	server := httptest.NewServer(bypass)
+	defer server.Close()
+
+	start := time.Now()
+	post(t, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestGroupHandler(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB per request
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	group := NewGroup[string](limit, time.Minute)
+	server := httptest.NewServer(GroupHandler(drainBody(t, dataSize), group, func(r *http.Request) string {
+		return r.URL.Path
+	}))
+	defer server.Close()
+
+	// both requests are keyed /a, sharing its budget
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		post(t, server.URL+"/a", dataSize)
+		close(done)
+	}()
+	post(t, server.URL+"/a", dataSize)
+	<-done
+	assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+}
+
+func TestServeContent_Range(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const partsAmount = 1
+	const limit = dataSize / 2 / partsAmount // half the data per second
+
+	data := make([]byte, dataSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	modtime := time.Now().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeContent(w, r, "data", modtime, bytes.NewReader(data), limit)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", dataSize/2))
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the skipped half costs nothing
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("unexpected status: %d expected: %d", resp.StatusCode, http.StatusPartialContent)
+	}
+	if !bytes.Equal(body, data[dataSize/2:]) {
+		t.Fatalf("unexpected range content")
+	}
+}
+
+func TestServeContent_IfRange(t *testing.T) {
+	const dataSize = 1024
+	const limit = dataSize * 20
+
+	modtime := time.Now().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeContent(w, r, "data", modtime, bytes.NewReader(make([]byte, dataSize)), limit)
+	}))
+	defer server.Close()
+
+	// a stale If-Range gets the whole content
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", dataSize/2))
+	req.Header.Set("If-Range", modtime.Add(-time.Hour).UTC().Format(http.TimeFormat))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || len(body) != dataSize {
+		t.Fatalf("unexpected response, status: %d size: %d expected: %d %d", resp.StatusCode, len(body), http.StatusOK, dataSize)
+	}
+}
+
+func drainBody(t *testing.T, expectedDataSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(data) != expectedDataSize {
+			t.Errorf("read incomplete data, read: %d expected: %d", len(data), expectedDataSize)
+		}
+	})
+}
+
+func post(t *testing.T, url string, dataSize int) {
+	resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(make([]byte, dataSize)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+}
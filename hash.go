@@ -0,0 +1,20 @@
+package ratelimitedreader
+
+import "hash"
+
+// WithHash feeds every byte the reader delivers into h as it goes, so
+// integrity-checked transfers need no second pass over the data. See Sum.
+func WithHash(h hash.Hash) Option {
+	return func(r *RateLimitedReader) {
+		r.hash = h
+	}
+}
+
+// Sum appends the hash of the bytes delivered so far to b, as h.Sum does for
+// the hash given to WithHash. Without one it returns b as is.
+func (r *RateLimitedReader) Sum(b []byte) []byte {
+	if r.hash == nil {
+		return b
+	}
+	return r.hash.Sum(b)
+}
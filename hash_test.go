@@ -0,0 +1,53 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"testing"
+)
+
+func TestRateLimitedReader_Hash(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB
+	const limit = dataSize * 20
+
+	data := make([]byte, dataSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	expected := sha256.Sum256(data)
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(data), limit, WithHash(sha256.New()))
+
+	// an unread byte is delivered twice but hashed once
+	ratelimitedReader.ReadByte()
+	ratelimitedReader.UnreadByte()
+	if _, err := io.Copy(io.Discard, ratelimitedReader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sum := ratelimitedReader.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("unexpected sum: %x expected: %x", sum, expected)
+	}
+}
+
+func TestRateLimitedReader_HashPollMode(t *testing.T) {
+	const dataSize = 1024
+	const limit = dataSize * 20
+
+	data := make([]byte, dataSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	expected := sha256.Sum256(data)
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(data), limit, WithHash(sha256.New()))
+	ratelimitedReader.SetPollMode(true)
+	if _, err := io.Copy(io.Discard, ratelimitedReader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sum := ratelimitedReader.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("unexpected sum: %x expected: %x", sum, expected)
+	}
+}
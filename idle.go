@@ -0,0 +1,21 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import "time"
+
+// WithIdleClose closes the reader, and so the underlying ReadCloser, once
+// timeout passes without a Read call, guarding servers against leaked client
+// streams. The timeout runs from construction and from the end of every
+// Read; reads after it fail with ErrClosed.
+func WithIdleClose(timeout time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		r.idleTimeout = timeout
+		r.idleTimer = time.AfterFunc(timeout, r.closeIdle)
+	}
+}
+
+func (r *RateLimitedReader) closeIdle() {
+	r.idleClosed.Store(true)
+	r.Close()
+}
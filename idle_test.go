@@ -0,0 +1,49 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_IdleClose(t *testing.T) {
+	const bufferSize = 1024
+	const idleTimeout = 200 * time.Millisecond
+
+	reader := &closeTracker{Reader: infiniteReader{}}
+	ratelimitedReader := NewRateLimitedReadCloser(reader, 0, WithIdleClose(idleTimeout))
+
+	// reads keep the reader open
+	for i := 0; i < 4; i++ {
+		time.Sleep(idleTimeout / 2)
+		if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if reader.closed.Load() {
+		t.Fatalf("expected the reader to stay open while read")
+	}
+
+	time.Sleep(idleTimeout * 2)
+	if !reader.closed.Load() {
+		t.Fatalf("expected the idle reader to be closed")
+	}
+	if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != ErrClosed {
+		t.Fatalf("unexpected error after idle close: %v expected: %v", err, ErrClosed)
+	}
+}
+
+// closeTracker records whether it was closed, safe to check while closed
+// from another goroutine.
+type closeTracker struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed.Store(true)
+	return nil
+}
@@ -0,0 +1,72 @@
+package ratelimitedreader
+
+import "time"
+
+// IdleReset is what a limiter does with its pacing state once nothing drew
+// from it for longer than its idle threshold, see Limiter.SetIdleReset.
+type IdleReset int
+
+const (
+	// IdleForgive starts afresh, the default: time booked ahead and the
+	// idle time are both dropped, so the first read after a pause waits its
+	// own time, as if nothing was read before.
+	IdleForgive IdleReset = iota
+
+	// IdleKeep never resets: all of the idle time is credited, first
+	// against time booked ahead, so a consumer coming back bursts as much as
+	// it saved up, however long it paused.
+	IdleKeep
+
+	// IdleCarryOver credits the idle time up to the threshold: time booked
+	// ahead is repaid first and what's left lets the next reads burst by at
+	// most the threshold's worth, so pauses neither forgive overages nor
+	// cost slow consumers a wait.
+	IdleCarryOver
+)
+
+// defaultIdleResetAfter is how long a limiter may sit idle before its idle
+// reset applies by default.
+const defaultIdleResetAfter = time.Second
+
+// SetIdleReset sets what l does once nothing drew from it for longer than
+// after, a second if after is 0 or below.
+func (l *Limiter) SetIdleReset(policy IdleReset, after time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.idleReset = policy
+	l.idleResetAfter = after
+}
+
+// WithIdleReset sets what the reader's limiter does once idle, see
+// Limiter.SetIdleReset, so on a shared limiter for all its readers.
+func WithIdleReset(policy IdleReset, after time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		r.limiter.SetIdleReset(policy, after)
+	}
+}
+
+// idleElapsed applies l's idle reset to elapsed, the time since the last
+// read, returning it as credited and whether l starts afresh. Must hold
+// l.mu.
+func (l *Limiter) idleElapsed(now, elapsed int64) (credited int64, reset bool) {
+	after := int64(l.idleResetAfter)
+	if after <= 0 {
+		after = int64(defaultIdleResetAfter)
+	}
+	if elapsed <= after {
+		return elapsed, false
+	}
+
+	switch {
+	case l.idleReset == IdleKeep && l.lastElapsed != 0:
+		return elapsed, false
+	case l.idleReset == IdleCarryOver && l.lastElapsed != 0:
+		// rebase on the credited time, so callers reading the state see it
+		l.lastElapsed = now - after
+		l.timeSlept = 0
+		return after, false
+	default:
+		return 0, true
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLimiter_IdleReset(t *testing.T) {
+	const limit = 10 * 1024
+	const after = 200 * time.Millisecond
+	const idle = 300 * time.Millisecond
+	const tolerance = 30 * time.Millisecond
+
+	for _, tc := range []struct {
+		name   string
+		policy IdleReset
+		// bytes is what's taken after the pause, and wait how long it
+		// should wait for them
+		bytes int64
+		wait  time.Duration
+	}{
+		// the pause is forgotten: even a single interval's budget waits
+		// for its interval
+		{"forgive", IdleForgive, limit / 20, 50 * time.Millisecond},
+		// all of the pause is credited, less the 50ms owed for the first
+		// budget: 400ms worth waits 150ms
+		{"keep", IdleKeep, limit * 2 / 5, 150 * time.Millisecond},
+		// the pause is credited up to the threshold: 400ms worth waits 200ms
+		{"carry over", IdleCarryOver, limit * 2 / 5, 200 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := NewLimiter(limit)
+			limiter.SetIdleReset(tc.policy, after)
+			iterLimit := limiter.iterLimit()
+
+			limiter.take(iterLimit, iterLimit)
+			time.Sleep(idle)
+
+			wait := limiter.take(tc.bytes, iterLimit)
+			if wait < tc.wait-tolerance || wait > tc.wait+tolerance {
+				t.Fatalf("unexpected wait after a pause: %v expected: %v", wait, tc.wait)
+			}
+		})
+	}
+}
+
+func TestLimiter_IdleCarryOverRepaysDebt(t *testing.T) {
+	const limit = 10 * 1024
+	const after = 200 * time.Millisecond
+	const tolerance = 30 * time.Millisecond
+
+	limiter := NewLimiter(limit)
+	limiter.SetIdleReset(IdleCarryOver, after)
+	iterLimit := limiter.iterLimit()
+
+	// book 500ms ahead without waiting for it, then pause 300ms: the pause
+	// repays 300ms of it, where forgiving would have dropped all of it
+	limiter.take(1, iterLimit)
+	limiter.take(limit/2, iterLimit)
+	time.Sleep(300 * time.Millisecond)
+
+	if wait := limiter.take(1, iterLimit); wait < 200*time.Millisecond-tolerance || wait > 200*time.Millisecond+tolerance {
+		t.Fatalf("unexpected wait for the rest of the debt: %v expected: %v", wait, 200*time.Millisecond)
+	}
+}
+
+func TestRateLimitedReader_IdleReset(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB
+	const bufferSize = 1024
+	const limit = dataSize // a second per dataSize
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize*2)), limit, WithIdleReset(IdleCarryOver, time.Second))
+	read(t, ratelimitedReader, bufferSize, dataSize)
+
+	// a slow consumer coming back from a pause reads what the pause saved
+	// up right away
+	time.Sleep(1500 * time.Millisecond)
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
@@ -0,0 +1,71 @@
+package ratelimitedreader
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Default bounds of WithAutoInterval, and the limits at which they're
+// reached.
+const (
+	defaultMinAutoInterval = 10 * time.Millisecond
+	defaultMaxAutoInterval = 250 * time.Millisecond
+
+	autoIntervalLowLimit  = 1 << 10 // 1KB/s
+	autoIntervalHighLimit = 1 << 30 // 1GB/s
+)
+
+// WithAutoInterval picks the reader's interval from its limit, and keeps
+// picking it as the limit changes: minInterval at 1KB/s and below for smooth
+// pacing of slow streams, maxInterval at 1GB/s and above for fewer wakeups on
+// fast ones, and in between on a log scale. Zero bounds default to 10ms and
+// 250ms. Like WithInterval it applies to the reader's limiter, and invalid
+// bounds are ignored and make NewReaderE fail with ErrInvalidLimit.
+func WithAutoInterval(minInterval, maxInterval time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		if err := r.limiter.SetAutoInterval(minInterval, maxInterval); err != nil {
+			r.optErr = err
+		}
+	}
+}
+
+// SetAutoInterval makes l pick its interval from its limit, see
+// WithAutoInterval. SetInterval switches back to a fixed interval.
+func (l *Limiter) SetAutoInterval(minInterval, maxInterval time.Duration) error {
+	if minInterval == 0 {
+		minInterval = defaultMinAutoInterval
+	}
+	if maxInterval == 0 {
+		maxInterval = defaultMaxAutoInterval
+	}
+	if err := validateInterval(minInterval); err != nil {
+		return err
+	}
+	if err := validateInterval(maxInterval); err != nil {
+		return err
+	}
+	if minInterval > maxInterval {
+		return fmt.Errorf("%w: min interval %v above max interval %v", ErrInvalidLimit, minInterval, maxInterval)
+	}
+
+	l.autoInterval.Store(&autoInterval{min: minInterval, max: maxInterval})
+	return nil
+}
+
+// autoInterval are the bounds of an interval picked by limit.
+type autoInterval struct {
+	min, max time.Duration
+}
+
+func (a *autoInterval) interval(limit int64) time.Duration {
+	if limit <= autoIntervalLowLimit {
+		return a.min
+	}
+	if limit >= autoIntervalHighLimit {
+		return a.max
+	}
+
+	scale := math.Log(float64(limit)/autoIntervalLowLimit) / math.Log(autoIntervalHighLimit/autoIntervalLowLimit)
+	return a.min + time.Duration(scale*float64(a.max-a.min))
+}
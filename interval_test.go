@@ -0,0 +1,67 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_AutoInterval(t *testing.T) {
+	limiter := NewLimiter(autoIntervalLowLimit)
+	if err := limiter.SetAutoInterval(0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interval := limiter.Interval(); interval != defaultMinAutoInterval {
+		t.Fatalf("unexpected interval at a low limit: %v expected: %v", interval, defaultMinAutoInterval)
+	}
+
+	limiter.SetLimit(autoIntervalHighLimit * 2)
+	if interval := limiter.Interval(); interval != defaultMaxAutoInterval {
+		t.Fatalf("unexpected interval at a high limit: %v expected: %v", interval, defaultMaxAutoInterval)
+	}
+
+	// halfway on a log scale
+	limiter.SetLimit(1 << 20)
+	if interval, expected := limiter.Interval(), (defaultMinAutoInterval+defaultMaxAutoInterval)/2; (interval - expected).Abs() > time.Millisecond {
+		t.Fatalf("unexpected interval at a medium limit: %v expected: %v", interval, expected)
+	}
+
+	if err := limiter.SetInterval(100 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interval := limiter.Interval(); interval != 100*time.Millisecond {
+		t.Fatalf("unexpected interval after setting a fixed one: %v", interval)
+	}
+}
+
+func TestLimiter_AutoIntervalInvalid(t *testing.T) {
+	limiter := NewLimiter(1024)
+	for _, bounds := range [][2]time.Duration{
+		{-time.Millisecond, 0},
+		{0, 2 * time.Second},
+		{200 * time.Millisecond, 100 * time.Millisecond},
+	} {
+		if err := limiter.SetAutoInterval(bounds[0], bounds[1]); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("unexpected error for bounds %v: %v expected: %v", bounds, err, ErrInvalidLimit)
+		}
+	}
+
+	if _, err := NewReaderE(bytes.NewReader(nil), 1024, WithAutoInterval(time.Second, time.Millisecond)); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for inverted bounds: %v expected: %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestRateLimitedReader_AutoInterval(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithAutoInterval(0, 0))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+}
@@ -0,0 +1,51 @@
+package ratelimitedreader
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// WithOnEOF calls fn once, with the final Stats, the first time a read
+// returns io.EOF, so bookkeeping such as marking a transfer complete lives
+// next to the reader. It's called from that read, before it returns.
+func WithOnEOF(fn func(stats Stats)) Option {
+	return WithOnEOFContext(func(_ context.Context, stats Stats) { fn(stats) })
+}
+
+// WithOnEOFContext is WithOnEOF calling fn with the context of the read
+// returning io.EOF, see ReadContext.
+func WithOnEOFContext(fn func(ctx context.Context, stats Stats)) Option {
+	return func(r *RateLimitedReader) {
+		r.onEOF = &lifecycleCallback{fn: fn}
+	}
+}
+
+// WithOnClose calls fn once, with the final Stats, the first time the
+// reader is closed, including by WithIdleClose. It's called from Close
+// before the underlying reader is closed.
+func WithOnClose(fn func(stats Stats)) Option {
+	return func(r *RateLimitedReader) {
+		r.onClose = &lifecycleCallback{fn: func(_ context.Context, stats Stats) { fn(stats) }}
+	}
+}
+
+// lifecycleCallback calls fn on its first fire only.
+type lifecycleCallback struct {
+	fn    func(ctx context.Context, stats Stats)
+	fired atomic.Bool
+}
+
+func (c *lifecycleCallback) fire(ctx context.Context, r *RateLimitedReader) {
+	if c == nil || c.fired.Swap(true) {
+		return
+	}
+	c.fn(ctx, r.Stats())
+}
+
+// ended fires the EOF callback once a read returns io.EOF.
+func (r *RateLimitedReader) ended(err error) {
+	if err == io.EOF {
+		r.onEOF.fire(r.context(), r)
+	}
+}
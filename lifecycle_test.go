@@ -0,0 +1,57 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRateLimitedReader_OnEOF(t *testing.T) {
+	const dataSize = 1024
+	const bufferSize = 256
+	const limit = dataSize * 10
+
+	var calls int
+	var final Stats
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithOnEOF(func(stats Stats) {
+		calls++
+		final = stats
+	}))
+
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != io.EOF {
+		t.Fatalf("unexpected error after EOF: %v expected: %v", err, io.EOF)
+	}
+
+	if calls != 1 {
+		t.Fatalf("unexpected calls: %d expected: 1", calls)
+	}
+	if final.DeliveredBytes != dataSize {
+		t.Fatalf("unexpected delivered bytes: %d expected: %d", final.DeliveredBytes, dataSize)
+	}
+}
+
+func TestRateLimitedReader_OnClose(t *testing.T) {
+	const dataSize = 1024
+	const bufferSize = 256
+
+	var calls int
+	var final Stats
+	ratelimitedReader := NewRateLimitedReadCloser(io.NopCloser(bytes.NewReader(make([]byte, dataSize))), 0, WithOnClose(func(stats Stats) {
+		calls++
+		final = stats
+	}))
+
+	if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ratelimitedReader.Close()
+	ratelimitedReader.Close()
+
+	if calls != 1 {
+		t.Fatalf("unexpected calls: %d expected: 1", calls)
+	}
+	if final.DeliveredBytes != bufferSize {
+		t.Fatalf("unexpected delivered bytes: %d expected: %d", final.DeliveredBytes, bufferSize)
+	}
+}
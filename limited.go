@@ -0,0 +1,23 @@
+package ratelimitedreader
+
+import "io"
+
+// NewLimitedN returns a reader pacing reader to limit that also caps the
+// transfer at maxBytes, as io.LimitReader does, returning io.EOF once they
+// were read. Unlike stacking an io.LimitReader on top, the cap applies to
+// the underlying reader itself: nothing past maxBytes is read from it, even
+// with read-ahead, so what's left stays unread, and the reader keeps its
+// Stats, Close and the rest. Close closes reader if it's an io.Closer.
+func NewLimitedN(reader io.Reader, limit, maxBytes int64, opts ...Option) *RateLimitedReader {
+	limited := io.NopCloser(io.LimitReader(reader, maxBytes))
+	if closer, ok := reader.(io.Closer); ok {
+		limited = limitedReadCloser{Reader: io.LimitReader(reader, maxBytes), Closer: closer}
+	}
+	return NewRateLimitedReadCloser(limited, limit, opts...)
+}
+
+// limitedReadCloser is a capped reader closing what it caps.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
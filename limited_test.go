@@ -0,0 +1,45 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewLimitedN(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const maxBytes = dataSize / 2
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = maxBytes / partsAmount
+
+	source := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewLimitedN(source, limit, maxBytes)
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, maxBytes)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	if n, err := ratelimitedReader.Read(make([]byte, bufferSize)); n != 0 || err != io.EOF {
+		t.Fatalf("unexpected read past the cap: %d error: %v", n, err)
+	}
+	// nothing past the cap was taken from the source
+	if source.Len() != dataSize-maxBytes {
+		t.Fatalf("unexpected bytes left in source: %d expected: %d", source.Len(), dataSize-maxBytes)
+	}
+}
+
+func TestNewLimitedN_Close(t *testing.T) {
+	readCloser := &mockReadCloser{}
+	if err := NewLimitedN(readCloser, 1024, 1024).Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !readCloser.closed {
+		t.Fatalf("expected the underlying reader to be closed")
+	}
+
+	if err := NewLimitedN(bytes.NewReader(nil), 1024, 1024).Close(); err != nil {
+		t.Fatalf("unexpected error closing a reader without Close: %v", err)
+	}
+}
@@ -0,0 +1,477 @@
+package ratelimitedreader
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter is the pacer behind RateLimitedReader. Its Allow/Reserve/Wait
+// methods mirror golang.org/x/time/rate.Limiter, where one token is one byte,
+// so code written against x/time/rate can be pointed at a reader's limiter.
+type Limiter struct {
+	limit atomic.Int64
+	// passthrough skips pacing while keeping limit, see SetPassthrough
+	passthrough atomic.Bool
+
+	mu              sync.Mutex
+	lastElapsed     int64
+	timeSlept       int64
+	timeAccumulated int64
+
+	// idleReset applies once idle for idleResetAfter, see SetIdleReset
+	idleReset      IdleReset
+	idleResetAfter time.Duration
+
+	// priorities orders readers' grants once one uses WithPriority
+	priorities atomic.Pointer[priorityQueue]
+
+	// interval is the pacing interval in nanoseconds, 0 for
+	// ReadIntervalMilliseconds, unless autoInterval picks it by limit
+	interval     atomic.Int64
+	autoInterval atomic.Pointer[autoInterval]
+
+	// waiters are the callers sleeping for their turn, see Waiters
+	waiters waiters
+}
+
+// Pacer reserves byte budgets for callers that don't move their bytes through
+// an io.Reader, such as custom protocols or message pumps. A Limiter is a
+// Pacer, so they can share budgets with readers.
+type Pacer interface {
+	WaitN(ctx context.Context, n int) error
+}
+
+// Acquirer adapts a Limiter to consumer loops of message systems, such as
+// Kafka, NATS or SQS consumers, that don't read through an io.Reader but
+// should keep to the same budgets as byte streams.
+type Acquirer struct {
+	limiter *Limiter
+}
+
+// NewAcquirer returns an Acquirer drawing from limiter, which may be shared
+// with readers and writers.
+func NewAcquirer(limiter *Limiter) *Acquirer {
+	return &Acquirer{limiter: limiter}
+}
+
+// Acquire blocks until nBytes, typically a message's size, may be consumed
+// or ctx is done, failing without booking them when ctx's deadline would
+// pass first.
+func (a *Acquirer) Acquire(ctx context.Context, nBytes int) error {
+	return a.limiter.WaitN(ctx, nBytes)
+}
+
+// Reservation holds bytes booked on a Limiter that may be used after Delay.
+// Canceling it hands the part of the budget not yet due back to the Limiter.
+type Reservation struct {
+	ok        bool
+	timeToAct time.Time
+
+	limiter *Limiter
+	// expected is how long the reserved bytes take at the limit
+	expected int64
+	canceled bool
+}
+
+var errWaitExceedsDeadline = errors.New("rate-limited-reader: wait would exceed context deadline")
+
+func NewLimiter(limit int64) *Limiter {
+	l := &Limiter{}
+	l.limit.Store(limit)
+	return l
+}
+
+// NewNopLimiter returns a limiter set to limit in passthrough mode, see
+// SetPassthrough.
+func NewNopLimiter(limit int64) *Limiter {
+	l := NewLimiter(limit)
+	l.SetPassthrough(true)
+	return l
+}
+
+// reset puts l back to a fresh state with newLimit.
+func (l *Limiter) reset(newLimit int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit.Store(newLimit)
+	l.lastElapsed = 0
+	l.timeSlept = 0
+	l.timeAccumulated = 0
+}
+
+func (l *Limiter) Limit() int64 {
+	return l.limit.Load()
+}
+
+func (l *Limiter) SetLimit(newLimit int64) {
+	l.limit.Store(newLimit)
+}
+
+// SetPassthrough turns pacing off, or back on, for everything drawing from
+// l, while keeping its limit, hooks and stats, so one code path can ship with
+// throttling toggled off, say for benchmarks. It takes effect on reads and
+// writes in flight from their next grant.
+func (l *Limiter) SetPassthrough(enabled bool) {
+	l.passthrough.Store(enabled)
+}
+
+// Passthrough reports whether l is in passthrough mode, see SetPassthrough.
+func (l *Limiter) Passthrough() bool {
+	return l.passthrough.Load()
+}
+
+// Interval returns the interval l paces in: each one's budget is granted
+// at once, so shorter intervals pace smoother and longer ones wake less.
+func (l *Limiter) Interval() time.Duration {
+	if auto := l.autoInterval.Load(); auto != nil {
+		return auto.interval(l.limit.Load())
+	}
+	if interval := l.interval.Load(); interval > 0 {
+		return time.Duration(interval)
+	}
+	return time.Duration(ReadIntervalMilliseconds) * time.Millisecond
+}
+
+// SetInterval sets the interval l paces in, returning ErrInvalidLimit for
+// one outside (0, 1s]. Intervals needn't divide a second; budgets are
+// computed from the limit per second either way. It may be changed on a
+// live limiter, to trade smoothness for fewer wakeups as rates change: what
+// was booked keeps its time, and readers sleeping on a grant rebook its rest
+// in the new interval's steps.
+func (l *Limiter) SetInterval(interval time.Duration) error {
+	if err := validateInterval(interval); err != nil {
+		return err
+	}
+
+	l.interval.Store(int64(interval))
+	l.autoInterval.Store(nil)
+	return nil
+}
+
+// Allow reports whether a single byte may be read now, booking it if so.
+// After an idle period the byte is granted at once and paid for by the
+// following call.
+func (l *Limiter) Allow() bool {
+	return l.allowN(time.Now(), 1)
+}
+
+// Reserve books a single byte and reports how long to wait before using it.
+// Like the reader, the first bytes after an idle period are paced rather than
+// burst.
+func (l *Limiter) Reserve() *Reservation {
+	return l.reserveN(time.Now(), 1)
+}
+
+// Wait blocks until a single byte may be read or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.waitN(ctx, 1)
+}
+
+// WaitN blocks until n bytes may be used or ctx is done. It fails without
+// booking the bytes when ctx's deadline would pass first.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	return l.waitN(ctx, int64(n))
+}
+
+// AllowN reports whether n bytes may be used at t, booking them if so, for
+// callers that would rather drop or defer work than block on the budget.
+func (l *Limiter) AllowN(t time.Time, n int) bool {
+	return l.allowN(t, int64(n))
+}
+
+// ReserveN books n bytes at t and reports how long to wait before using
+// them. Cancel the reservation to return bytes that won't be used.
+func (l *Limiter) ReserveN(t time.Time, n int) *Reservation {
+	return l.reserveN(t, int64(n))
+}
+
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+func (r *Reservation) DelayFrom(t time.Time) time.Duration {
+	if !r.ok {
+		return 0
+	}
+
+	delay := r.timeToAct.Sub(t)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Cancel returns the reserved bytes to the Limiter as far as their time is
+// not yet due, so later reservations don't wait for them.
+func (r *Reservation) Cancel() {
+	r.CancelAt(time.Now())
+}
+
+func (r *Reservation) CancelAt(t time.Time) {
+	if !r.ok || r.limiter == nil {
+		return
+	}
+
+	r.limiter.mu.Lock()
+	defer r.limiter.mu.Unlock()
+
+	if r.canceled {
+		return
+	}
+	r.canceled = true
+
+	unused := int64(r.timeToAct.Sub(t))
+	if unused <= 0 {
+		return
+	}
+
+	unused = min(unused, r.expected)
+	r.limiter.timeAccumulated -= unused
+}
+
+func (l *Limiter) allowN(t time.Time, n int64) bool {
+	iterLimit := l.iterLimit()
+	if iterLimit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := t.UnixNano()
+	sleepTime, _, reset := l.delay(now, n, iterLimit)
+	if reset {
+		l.grantIdle(now, n, iterLimit)
+		return true
+	}
+
+	if sleepTime > 0 {
+		return false
+	}
+
+	l.book(now, n, iterLimit)
+	return true
+}
+
+func (l *Limiter) reserveN(t time.Time, n int64) *Reservation {
+	iterLimit := l.iterLimit()
+	if iterLimit <= 0 {
+		return &Reservation{ok: true, timeToAct: t}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sleepTime := l.book(t.UnixNano(), n, iterLimit)
+	return &Reservation{
+		ok:        true,
+		timeToAct: t.Add(sleepTime),
+		limiter:   l,
+		expected:  l.expectedTime(n, iterLimit),
+	}
+}
+
+func (l *Limiter) waitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r := l.reserveN(now, n)
+	delay := r.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		r.CancelAt(now)
+		return errWaitExceedsDeadline
+	}
+
+	defer l.enqueue(n)()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// tryTake books as many bytes as are available now, up to max, without the
+// caller having to sleep, and returns how many.
+func (l *Limiter) tryTake(max, iterLimit int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	_, elapsed, reset := l.delay(now, 0, iterLimit)
+	if reset {
+		n := min(max, iterLimit)
+		l.grantIdle(now, n, iterLimit)
+		return n
+	}
+
+	available := mulDiv(elapsed-l.timeAccumulated, iterLimit, int64(l.Interval()))
+	n := min(max, available)
+	if n <= 0 {
+		return 0
+	}
+
+	l.book(now, n, iterLimit)
+	return n
+}
+
+// nextTime returns when n bytes may be used without sleeping.
+func (l *Limiter) nextTime(n, iterLimit int64) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	sleepTime, _, reset := l.delay(now.UnixNano(), n, iterLimit)
+	if reset || sleepTime <= 0 {
+		return now
+	}
+	return now.Add(time.Duration(sleepTime))
+}
+
+// grantIdle books n bytes on an idle window without making the caller sleep,
+// carrying their time as debt into the next call instead. Must hold l.mu.
+func (l *Limiter) grantIdle(now, n, iterLimit int64) {
+	l.lastElapsed = now
+	l.timeSlept = 0
+	l.timeAccumulated = l.expectedTime(n, iterLimit)
+}
+
+// take books n bytes now and returns how long the caller should sleep before
+// reading them.
+func (l *Limiter) take(n, iterLimit int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.book(time.Now().UnixNano(), n, iterLimit)
+}
+
+// adopt takes over what other has booked, so l paces on where other left
+// off.
+func (l *Limiter) adopt(other *Limiter) {
+	other.mu.Lock()
+	lastElapsed, timeSlept, timeAccumulated := other.lastElapsed, other.timeSlept, other.timeAccumulated
+	other.mu.Unlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastElapsed, l.timeSlept, l.timeAccumulated = lastElapsed, timeSlept, timeAccumulated
+}
+
+// refund credits back n bytes taken but never read.
+func (l *Limiter) refund(n, iterLimit int64) {
+	l.refundTime(time.Duration(l.expectedTime(n, iterLimit)))
+}
+
+// refundTime credits back d of booked time that won't be used.
+func (l *Limiter) refundTime(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.timeAccumulated -= int64(d)
+}
+
+// iterLimit returns the limit per read interval, or 0 when unlimited.
+func (l *Limiter) iterLimit() int64 {
+	limit := l.limit.Load()
+	if limit <= 0 || l.passthrough.Load() {
+		return 0
+	}
+
+	// the limit set to per second, scaled to the interval
+	return mulDiv(limit, int64(l.Interval()), int64(time.Second))
+}
+
+// lastActive returns the time up to which l has bytes booked, the zero
+// time if it never booked any.
+func (l *Limiter) lastActive() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lastElapsed == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, l.lastElapsed+l.timeSlept)
+}
+
+// delay returns how long reading n bytes at now must wait without booking
+// them, the elapsed time since the last read, and whether the pacing window
+// has gone idle long enough to be reset, see SetIdleReset. Must hold l.mu.
+func (l *Limiter) delay(now, n, iterLimit int64) (sleepTime, elapsed int64, reset bool) {
+	accumulated := l.timeAccumulated
+	elapsed, reset = l.idleElapsed(now, now-l.lastElapsed-l.timeSlept)
+	if reset {
+		accumulated = 0
+	}
+
+	return accumulated - (elapsed - l.expectedTime(n, iterLimit)), elapsed, reset
+}
+
+// expectedTime returns how long reading n bytes takes at iterLimit bytes per
+// interval, in nanoseconds.
+func (l *Limiter) expectedTime(n, iterLimit int64) int64 {
+	return mulDiv(n, int64(l.Interval()), iterLimit)
+}
+
+// mulDiv returns a*b/c for non-negative b and positive c, computed in 128
+// bits so big limits and byte counts can't overflow the product, and
+// saturating at the int64 range.
+func mulDiv(a, b, c int64) int64 {
+	if a < 0 {
+		return -mulDiv(-a, b, c)
+	}
+
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		return math.MaxInt64
+	}
+
+	quotient, _ := bits.Div64(hi, lo, uint64(c))
+	return int64(min(quotient, math.MaxInt64))
+}
+
+// book accounts n bytes read at now and returns how long the caller should
+// sleep before reading them. Must hold l.mu.
+func (l *Limiter) book(now, n, iterLimit int64) time.Duration {
+	sleepTime, elapsed, reset := l.delay(now, n, iterLimit)
+	if reset {
+		l.lastElapsed = now
+		l.timeSlept = 0
+		l.timeAccumulated = 0
+	}
+
+	if sleepTime > 0 {
+		l.timeAccumulated = 0
+		if elapsed == 0 {
+			l.timeSlept += sleepTime
+		} else {
+			l.timeSlept = 0
+			l.lastElapsed = now + sleepTime
+		}
+	} else {
+		l.timeAccumulated = sleepTime
+		l.timeSlept = 0
+		l.lastElapsed = now
+	}
+
+	return time.Duration(sleepTime)
+}
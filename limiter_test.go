@@ -0,0 +1,372 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	const limit = 1000 // 1ms per byte
+
+	limiter := NewLimiter(limit)
+	if !limiter.Allow() {
+		t.Fatalf("expected first byte on an idle limiter to be allowed")
+	}
+
+	if limiter.Allow() {
+		t.Fatalf("expected second byte to be denied until its time is due")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatalf("expected byte to be allowed after waiting")
+	}
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	const limit = 20 * 1024
+	const chunkSize = limit / 10 // a tenth of a second each
+
+	limiter := NewLimiter(limit)
+	now := time.Now()
+	if !limiter.AllowN(now, chunkSize) {
+		t.Fatalf("expected first chunk on an idle limiter to be allowed")
+	}
+
+	if limiter.AllowN(now.Add(time.Millisecond), chunkSize) {
+		t.Fatalf("expected second chunk to be denied until its time is due")
+	}
+
+	if !limiter.AllowN(now.Add(2*time.Second/10), chunkSize) {
+		t.Fatalf("expected chunk to be allowed once its time is due")
+	}
+}
+
+func TestLimiter_NoLimitAllow(t *testing.T) {
+	limiter := NewLimiter(0)
+	for i := 0; i < 1000; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("expected unlimited limiter to always allow, denied at i=%d", i)
+		}
+	}
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	limit := 1000 / ReadIntervalMilliseconds // one byte per interval
+	interval := time.Duration(ReadIntervalMilliseconds) * time.Millisecond
+
+	limiter := NewLimiter(limit)
+	first := limiter.Reserve()
+	second := limiter.Reserve()
+	if !first.OK() || !second.OK() {
+		t.Fatalf("expected reservations to be ok")
+	}
+
+	if first.Delay() > interval {
+		t.Fatalf("first reservation delay too long: %v > %v", first.Delay(), interval)
+	}
+
+	if second.Delay() <= first.Delay() {
+		t.Fatalf("expected second reservation to wait longer than the first, got %v <= %v", second.Delay(), first.Delay())
+	}
+}
+
+func TestLimiter_ReserveNCancel(t *testing.T) {
+	const limit = 20 * 1024
+	const chunkSize = limit / 2 // half a second each
+
+	limiter := NewLimiter(limit)
+	now := time.Now()
+	first := limiter.ReserveN(now, chunkSize)
+	second := limiter.ReserveN(now, chunkSize)
+	if second.DelayFrom(now) <= first.DelayFrom(now) {
+		t.Fatalf("expected second reservation to wait longer than the first, got %v <= %v", second.DelayFrom(now), first.DelayFrom(now))
+	}
+
+	second.CancelAt(now)
+	third := limiter.ReserveN(now, chunkSize)
+	if third.DelayFrom(now) != second.DelayFrom(now) {
+		t.Fatalf("expected canceled budget to be reused, delay: %v expected: %v", third.DelayFrom(now), second.DelayFrom(now))
+	}
+
+	// canceling twice returns nothing more
+	second.CancelAt(now)
+	fourth := limiter.ReserveN(now, chunkSize)
+	if fourth.DelayFrom(now) <= third.DelayFrom(now) {
+		t.Fatalf("expected double cancel to be a no-op, delay: %v <= %v", fourth.DelayFrom(now), third.DelayFrom(now))
+	}
+}
+
+func TestLimiter_CancelAfterDue(t *testing.T) {
+	const limit = 20 * 1024
+	const chunkSize = limit / 2 // half a second each
+
+	limiter := NewLimiter(limit)
+	now := time.Now()
+	first := limiter.ReserveN(now, chunkSize)
+	second := limiter.ReserveN(now, chunkSize)
+
+	// the first reservation is already used by the time it's canceled
+	first.CancelAt(now.Add(first.DelayFrom(now)))
+	third := limiter.ReserveN(now, chunkSize)
+	if third.DelayFrom(now) <= second.DelayFrom(now) {
+		t.Fatalf("expected used reservation to keep its budget, delay: %v <= %v", third.DelayFrom(now), second.DelayFrom(now))
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	limit := 1000 / ReadIntervalMilliseconds // one byte per interval
+	interval := time.Duration(ReadIntervalMilliseconds) * time.Millisecond
+
+	limiter := NewLimiter(limit)
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < interval/2 {
+		t.Fatalf("wait returned too quickly: %v", elapsed)
+	}
+}
+
+func TestLimiter_WaitExceedsDeadline(t *testing.T) {
+	limit := 1000 / ReadIntervalMilliseconds // one byte per interval
+
+	limiter := NewLimiter(limit)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); err == nil {
+		t.Fatalf("expected error when the wait can't finish before the deadline")
+	}
+}
+
+func TestLimiter_WaitN(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const chunkSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var pacer Pacer = NewLimiter(limit)
+	start := time.Now()
+	for i := 0; i < dataSize/chunkSize; i++ {
+		if err := pacer.WaitN(context.Background(), chunkSize); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestLimiter_WaitNSharedWithReader(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB read and 20KB waited
+	const bufferSize = 1024
+	const limit = dataSize // a second each
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit)
+	start := time.Now()
+	done := make(chan error)
+	go func() {
+		done <- ratelimitedReader.Limiter().WaitN(context.Background(), dataSize)
+	}()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertReadTimes(t, time.Since(start), 2, 3)
+}
+
+func TestLimiter_WaitNCanceled(t *testing.T) {
+	const limit = 1024
+
+	limiter := NewLimiter(limit)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := limiter.WaitN(ctx, limit); err != context.Canceled {
+		t.Fatalf("unexpected error: %v expected: %v", err, context.Canceled)
+	}
+}
+
+func TestRateLimitedReader_Limiter(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(nil, limit)
+	if ratelimitedReader.Limiter().Limit() != limit {
+		t.Fatalf("unexpected limiter limit: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit)
+	}
+
+	ratelimitedReader.UpdateLimit(limit * 2)
+	if ratelimitedReader.Limiter().Limit() != limit*2 {
+		t.Fatalf("unexpected limiter limit after update: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit*2)
+	}
+}
+
+func TestLimiter_BigLimits(t *testing.T) {
+	const limit = 1 << 42 // 4TB per second
+	iterLimit := int64(limit / (1000 / ReadIntervalMilliseconds))
+
+	// bytes worth many intervals would overflow a plain int64 product
+	if expected := NewLimiter(0).expectedTime(iterLimit*4, iterLimit); expected != 4*ReadIntervalMilliseconds*int64(time.Millisecond) {
+		t.Fatalf("unexpected expected time: %v", time.Duration(expected))
+	}
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit)
+	start := time.Now()
+	read(t, ratelimitedReader, 1<<20, 1<<20)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestLimiter_Interval(t *testing.T) {
+	const limit = 30000
+
+	limiter := NewLimiter(limit)
+	if interval := limiter.Interval(); interval != time.Duration(ReadIntervalMilliseconds)*time.Millisecond {
+		t.Fatalf("unexpected default interval: %v", interval)
+	}
+
+	// 33ms doesn't divide a second, the budget must still add up to limit
+	if err := limiter.SetInterval(33 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if iterLimit := limiter.iterLimit(); iterLimit != limit*33/1000 {
+		t.Fatalf("unexpected iter limit: %d expected: %d", iterLimit, limit*33/1000)
+	}
+
+	for _, interval := range []time.Duration{0, -time.Millisecond, 2 * time.Second} {
+		if err := limiter.SetInterval(interval); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("unexpected error for interval %v: %v expected: %v", interval, err, ErrInvalidLimit)
+		}
+	}
+}
+
+func TestAcquirer(t *testing.T) {
+	const messageSize = 1024
+	const messagesAmount = 20
+	const partsAmount = 2
+	const limit = messageSize * messagesAmount / partsAmount // messageSize*messagesAmount/partsAmount bytes per second
+
+	// shares the budget with a reader reading as much
+	limiter := NewLimiter(limit)
+	ratelimitedReader := NewReaderWithLimiter(bytes.NewReader(make([]byte, messageSize*messagesAmount)), limiter)
+	acquirer := NewAcquirer(limiter)
+
+	start := time.Now()
+	done := make(chan error)
+	go func() {
+		for i := 0; i < messagesAmount; i++ {
+			if err := acquirer.Acquire(context.Background(), messageSize); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	read(t, ratelimitedReader, messageSize, messageSize*messagesAmount)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+}
+
+func TestLimiter_Waiters(t *testing.T) {
+	const limit = 1024
+	const readersAmount = 3
+
+	limiter := NewLimiter(limit)
+	if waiters := limiter.Waiters(); len(waiters) != 0 {
+		t.Fatalf("unexpected waiters on an idle limiter: %v", waiters)
+	}
+
+	readers := make([]*RateLimitedReader, readersAmount)
+	done := make(chan struct{})
+	for i := range readers {
+		readers[i] = NewReaderWithLimiter(infiniteReader{}, limiter)
+		go func() {
+			defer func() { done <- struct{}{} }()
+			buf := make([]byte, limit)
+			for {
+				if _, err := readers[i].Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	// the readers queue up behind each other
+	deadline := time.Now().Add(time.Second)
+	waiters := limiter.Waiters()
+	for len(waiters) < readersAmount && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		waiters = limiter.Waiters()
+	}
+	if len(waiters) != readersAmount {
+		t.Fatalf("unexpected waiters: %v expected: %d", waiters, readersAmount)
+	}
+	for i, waiter := range waiters {
+		if waiter.Bytes <= 0 || (i > 0 && waiter.Since.Before(waiters[i-1].Since)) {
+			t.Fatalf("unexpected waiters: %v", waiters)
+		}
+	}
+
+	for _, reader := range readers {
+		reader.Close()
+	}
+	for range readers {
+		<-done
+	}
+	if waiters := limiter.Waiters(); len(waiters) != 0 {
+		t.Fatalf("unexpected waiters once closed: %v", waiters)
+	}
+}
+
+func TestLimiter_Passthrough(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const limit = dataSize / 2
+
+	var eofStats []Stats
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize*2)), limit, WithPassthrough(), WithOnEOF(func(stats Stats) {
+		eofStats = append(eofStats, stats)
+	}))
+
+	// passing through, the reader keeps its limit and stats but doesn't pace
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+	if stats := ratelimitedReader.Stats(); stats.Limit != limit || stats.DeliveredBytes != dataSize {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// toggled back, it paces again
+	ratelimitedReader.Limiter().SetPassthrough(false)
+	start = time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+	if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != io.EOF || len(eofStats) != 1 {
+		t.Fatalf("unexpected end: %v EOF hook calls: %d", err, len(eofStats))
+	}
+}
+
+func TestNewNopLimiter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const limit = dataSize / 2
+
+	limiter := NewNopLimiter(limit)
+	if !limiter.Passthrough() || limiter.Limit() != limit {
+		t.Fatalf("unexpected nop limiter, passthrough: %v limit: %d", limiter.Passthrough(), limiter.Limit())
+	}
+
+	start := time.Now()
+	if err := limiter.WaitN(context.Background(), dataSize); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer := NewWriterWithLimiter(io.Discard, limiter)
+	if _, err := writer.Write(make([]byte, dataSize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
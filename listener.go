@@ -0,0 +1,110 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// RateLimitedListener wraps every accepted conn in a RateLimitedConn and can
+// also cap how many conns are accepted per second, so a connection flood
+// can't get around the byte limits.
+type RateLimitedListener struct {
+	net.Listener
+	readLimit  int64
+	writeLimit int64
+	connOpts   []ConnOption
+
+	acceptMu     sync.Mutex
+	acceptLimit  int64
+	acceptBurst  int64
+	acceptTokens float64
+	lastAccept   time.Time
+
+	// closed is closed by Close, cutting short Accepts waiting on the
+	// accept limit
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewRateLimitedListener returns a listener wrapping accepted conns with
+// opts.
+func NewRateLimitedListener(listener net.Listener, readLimit, writeLimit int64, opts ...ConnOption) *RateLimitedListener {
+	return &RateLimitedListener{
+		Listener:   listener,
+		readLimit:  readLimit,
+		writeLimit: writeLimit,
+		connOpts:   opts,
+		closed:     make(chan struct{}),
+	}
+}
+
+// Accept waits until the accept limit allows another conn, then accepts it.
+// Closing the listener ends the wait with net.ErrClosed.
+func (l *RateLimitedListener) Accept() (net.Conn, error) {
+	if sleepTime := l.reserveAccept(); sleepTime > 0 {
+		timer := time.NewTimer(sleepTime)
+		select {
+		case <-timer.C:
+		case <-l.closed:
+			timer.Stop()
+			return nil, net.ErrClosed
+		}
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRateLimitedConn(conn, l.readLimit, l.writeLimit, l.connOpts...), nil
+}
+
+// Close closes the listener, ending Accepts waiting on the accept limit.
+func (l *RateLimitedListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// UpdateAcceptLimit caps accepted conns to limit per second, allowing bursts
+// of up to burst conns after a quiet period. A limit of 0 or below removes the
+// cap.
+func (l *RateLimitedListener) UpdateAcceptLimit(limit, burst int64) {
+	l.acceptMu.Lock()
+	defer l.acceptMu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.acceptLimit = limit
+	l.acceptBurst = burst
+	l.acceptTokens = float64(burst)
+	l.lastAccept = time.Now()
+}
+
+// reserveAccept takes a token for the next accept and returns how long to
+// wait before it's due.
+func (l *RateLimitedListener) reserveAccept() time.Duration {
+	l.acceptMu.Lock()
+	defer l.acceptMu.Unlock()
+
+	if l.acceptLimit <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.acceptTokens += now.Sub(l.lastAccept).Seconds() * float64(l.acceptLimit)
+	if l.acceptTokens > float64(l.acceptBurst) {
+		l.acceptTokens = float64(l.acceptBurst)
+	}
+	l.lastAccept = now
+
+	l.acceptTokens--
+	if l.acceptTokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.acceptTokens / float64(l.acceptLimit) * float64(time.Second))
+}
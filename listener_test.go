@@ -0,0 +1,176 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedListener_WrapsConns(t *testing.T) {
+	const limit = 1024
+
+	listener := listen(t)
+	defer listener.Close()
+	ratelimitedListener := NewRateLimitedListener(listener, limit, limit*2)
+
+	dial(t, listener.Addr(), 1)
+	conn, err := ratelimitedListener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	ratelimitedConn, ok := conn.(*RateLimitedConn)
+	if !ok {
+		t.Fatalf("expected accepted conn to be rate limited, got %T", conn)
+	}
+	if ratelimitedConn.readLimiter.Limit() != limit || ratelimitedConn.writeLimiter.Limit() != limit*2 {
+		t.Fatalf("unexpected conn limits, read: %d write: %d expected: %d %d",
+			ratelimitedConn.readLimiter.Limit(), ratelimitedConn.writeLimiter.Limit(), limit, limit*2)
+	}
+}
+
+// proxyConn stands for a proxy protocol conn, which reports the client's
+// address and hands out the conn it wraps through NetConn.
+type proxyConn struct {
+	net.Conn
+}
+
+func (c proxyConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+func TestRateLimitedListener_TCPConn(t *testing.T) {
+	listener := listen(t)
+	defer listener.Close()
+	ratelimitedListener := NewRateLimitedListener(listener, 1024, 1024)
+
+	dial(t, listener.Addr(), 1)
+	conn, err := ratelimitedListener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	for _, ratelimitedConn := range []*RateLimitedConn{
+		conn.(*RateLimitedConn),
+		NewRateLimitedConn(proxyConn{conn.(*RateLimitedConn).Conn}, 0, 0),
+	} {
+		tcpConn, ok := ratelimitedConn.TCPConn()
+		if !ok {
+			t.Fatalf("expected a TCP conn underneath %T", ratelimitedConn.Conn)
+		}
+		if err := tcpConn.SetNoDelay(false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := tcpConn.SetKeepAlive(true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	pipe, remote := net.Pipe()
+	defer pipe.Close()
+	defer remote.Close()
+	if _, ok := NewRateLimitedConn(pipe, 0, 0).TCPConn(); ok {
+		t.Fatalf("expected no TCP conn underneath a pipe")
+	}
+}
+
+func TestRateLimitedListener_AcceptLimit(t *testing.T) {
+	const acceptLimit = 10 // conns per second
+	const burst = 5
+	const connsAmount = burst + acceptLimit // a burst then a second's worth
+
+	listener := listen(t)
+	defer listener.Close()
+	ratelimitedListener := NewRateLimitedListener(listener, 0, 0)
+	ratelimitedListener.UpdateAcceptLimit(acceptLimit, burst)
+
+	dial(t, listener.Addr(), connsAmount)
+
+	start := time.Now()
+	for i := 0; i < connsAmount; i++ {
+		conn, err := ratelimitedListener.Accept()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+
+		if i == burst-1 {
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Fatalf("burst accepted too slowly: %v", elapsed)
+			}
+		}
+	}
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
+
+func TestRateLimitedListener_CloseDuringAcceptWait(t *testing.T) {
+	const acceptLimit = 1 // conns per second
+
+	listener := listen(t)
+	ratelimitedListener := NewRateLimitedListener(listener, 0, 0)
+	ratelimitedListener.UpdateAcceptLimit(acceptLimit, 1)
+
+	dial(t, listener.Addr(), 2)
+	conn, err := ratelimitedListener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ratelimitedListener.Close()
+	}()
+
+	start := time.Now()
+	if _, err := ratelimitedListener.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("unexpected error: %v expected: %v", err, net.ErrClosed)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("closed listener kept waiting on the accept limit: %v", elapsed)
+	}
+}
+
+func TestRateLimitedListener_NoAcceptLimit(t *testing.T) {
+	const connsAmount = 50
+
+	listener := listen(t)
+	defer listener.Close()
+	ratelimitedListener := NewRateLimitedListener(listener, 0, 0)
+
+	dial(t, listener.Addr(), connsAmount)
+
+	start := time.Now()
+	for i := 0; i < connsAmount; i++ {
+		conn, err := ratelimitedListener.Accept()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error while listening: %v", err)
+	}
+	return listener
+}
+
+// dial opens amount conns to addr, closing them when the test ends.
+func dial(t *testing.T, addr net.Addr, amount int) {
+	for i := 0; i < amount; i++ {
+		conn, err := net.Dial(addr.Network(), addr.String())
+		if err != nil {
+			t.Fatalf("unexpected error while dialing: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+}
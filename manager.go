@@ -0,0 +1,239 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Manager tracks rate-limited readers and conns by name so an operator can
+// tune their limits from one place while they are in use.
+type Manager struct {
+	mu      sync.RWMutex
+	readers map[string]*RateLimitedReader
+	conns   map[string]*RateLimitedConn
+
+	// limits are the unscaled limits of the readers' limiters, each
+	// recorded once however many readers share it, and of the conns'
+	// directions, which scale multiplies, see ScaleAll
+	readerLimits map[*Limiter]int64
+	connLimits   map[string][2]int64
+	scale        float64
+
+	// tenants files readers under a tenant, see SetTenant
+	tenants map[string]string
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		readers:      make(map[string]*RateLimitedReader),
+		conns:        make(map[string]*RateLimitedConn),
+		readerLimits: make(map[*Limiter]int64),
+		connLimits:   make(map[string][2]int64),
+		scale:        1,
+		tenants:      make(map[string]string),
+	}
+}
+
+// AddReader registers reader under name, replacing any reader already there.
+// Its limit is scaled by the current ScaleAll factor, unless its limiter is
+// shared with a reader already registered and so already scaled.
+func (m *Manager) AddReader(name string, reader *RateLimitedReader) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.readers[name]
+	m.readers[name] = reader
+	if old != nil {
+		m.forgetLimiter(old.Limiter())
+	}
+
+	limiter := reader.Limiter()
+	if _, ok := m.readerLimits[limiter]; !ok {
+		m.readerLimits[limiter] = limiter.Limit()
+		limiter.SetLimit(m.scaled(m.readerLimits[limiter]))
+	}
+}
+
+// forgetLimiter drops the unscaled limit of limiter once no registered
+// reader draws from it. Must hold m.mu.
+func (m *Manager) forgetLimiter(limiter *Limiter) {
+	for _, reader := range m.readers {
+		if reader.Limiter() == limiter {
+			return
+		}
+	}
+	delete(m.readerLimits, limiter)
+}
+
+// AddConn registers conn under name, replacing any conn already there. Its
+// limits are scaled by the current ScaleAll factor.
+func (m *Manager) AddConn(name string, conn *RateLimitedConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.conns[name] = conn
+	m.connLimits[name] = [2]int64{conn.readLimiter.Limit(), conn.writeLimiter.Limit()}
+	m.scaleConn(name, conn)
+}
+
+// Remove unregisters the reader and conn under name, and its tenant.
+func (m *Manager) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if reader, ok := m.readers[name]; ok {
+		delete(m.readers, name)
+		m.forgetLimiter(reader.Limiter())
+	}
+	delete(m.conns, name)
+	delete(m.connLimits, name)
+	delete(m.tenants, name)
+}
+
+// UpdateLimit changes the limit of the reader under name, reporting whether
+// one was registered. The limit is scaled by the current ScaleAll factor.
+// Readers sharing its limiter change along with it.
+func (m *Manager) UpdateLimit(name string, newLimit int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reader, ok := m.readers[name]
+	if ok {
+		m.readerLimits[reader.Limiter()] = newLimit
+		reader.UpdateLimit(m.scaled(newLimit))
+	}
+	return ok
+}
+
+// UpdateReadLimit changes the read limit of the conn under name, reporting
+// whether one was registered. The limit is scaled by the current ScaleAll
+// factor.
+func (m *Manager) UpdateReadLimit(name string, newLimit int64) bool {
+	return m.updateConnLimit(name, 0, newLimit)
+}
+
+// UpdateWriteLimit changes the write limit of the conn under name, reporting
+// whether one was registered. The limit is scaled by the current ScaleAll
+// factor.
+func (m *Manager) UpdateWriteLimit(name string, newLimit int64) bool {
+	return m.updateConnLimit(name, 1, newLimit)
+}
+
+// updateConnLimit changes the conn's read limit, direction 0, or write limit,
+// direction 1.
+func (m *Manager) updateConnLimit(name string, direction int, newLimit int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, ok := m.conns[name]
+	if ok {
+		limits := m.connLimits[name]
+		limits[direction] = newLimit
+		if conn.shared {
+			limits = [2]int64{newLimit, newLimit}
+		}
+		m.connLimits[name] = limits
+		m.scaleConn(name, conn)
+	}
+	return ok
+}
+
+// ScaleAll sets every registered limit to factor times its unscaled limit,
+// the one it was registered with or last updated to through the Manager,
+// e.g. 0.5 to slow everything to half during an incident and 1 to restore
+// it. Limits changed other than through the Manager are overwritten.
+// Unlimited readers and conns stay unlimited. All limits change under one
+// lock, so no update through the Manager interleaves. It returns
+// ErrInvalidLimit for a factor that isn't positive and finite.
+func (m *Manager) ScaleAll(factor float64) error {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return fmt.Errorf("%w: scale factor %v", ErrInvalidLimit, factor)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scale = factor
+	for limiter, limit := range m.readerLimits {
+		limiter.SetLimit(m.scaled(limit))
+	}
+	for name, conn := range m.conns {
+		m.scaleConn(name, conn)
+	}
+	return nil
+}
+
+// Scale returns the factor set by ScaleAll, 1 if never set.
+func (m *Manager) Scale() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.scale
+}
+
+// ScaleHandler serves the ScaleAll factor over HTTP, for incident tooling:
+// GET returns it, and POST or PUT with a factor query parameter, e.g.
+// ?factor=0.5, sets it.
+func (m *Manager) ScaleHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut:
+			factor, err := strconv.ParseFloat(r.URL.Query().Get("factor"), 64)
+			if err == nil {
+				err = m.ScaleAll(factor)
+			}
+			if err != nil {
+				http.Error(w, "invalid factor: "+r.URL.Query().Get("factor"), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		fmt.Fprintln(w, strconv.FormatFloat(m.Scale(), 'g', -1, 64))
+	})
+}
+
+// scaled returns limit scaled by the current factor, keeping limits at least
+// 1 so scaling never lifts them. Must hold m.mu.
+func (m *Manager) scaled(limit int64) int64 {
+	if limit <= 0 || m.scale == 1 {
+		return limit
+	}
+	return max(int64(float64(limit)*m.scale), 1)
+}
+
+// scaleConn applies the scaled limits to the conn under name. Must hold
+// m.mu.
+func (m *Manager) scaleConn(name string, conn *RateLimitedConn) {
+	limits := m.connLimits[name]
+	if conn.shared {
+		// both directions are one limiter
+		conn.UpdateReadLimit(m.scaled(limits[0]))
+		return
+	}
+	conn.UpdateReadLimit(m.scaled(limits[0]))
+	conn.UpdateWriteLimit(m.scaled(limits[1]))
+}
+
+// DumpJSON returns the Stats of every registered reader as a JSON object
+// keyed by name, for agents shipping periodic snapshots.
+func (m *Manager) DumpJSON() ([]byte, error) {
+	m.mu.RLock()
+	stats := make(map[string]Stats, len(m.readers))
+	for name, reader := range m.readers {
+		stats[name] = reader.Stats()
+	}
+	m.mu.RUnlock()
+
+	return json.Marshal(stats)
+}
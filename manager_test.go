@@ -0,0 +1,280 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManager_UpdateLimit(t *testing.T) {
+	const limit = 1024
+
+	manager := NewManager()
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	manager.AddReader("reader", ratelimitedReader)
+
+	if !manager.UpdateLimit("reader", limit*2) {
+		t.Fatalf("expected registered reader to be updated")
+	}
+	if ratelimitedReader.Limiter().Limit() != limit*2 {
+		t.Fatalf("unexpected limit: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit*2)
+	}
+
+	if manager.UpdateLimit("missing", limit) {
+		t.Fatalf("expected update of unregistered reader to fail")
+	}
+
+	manager.Remove("reader")
+	if manager.UpdateLimit("reader", limit) {
+		t.Fatalf("expected update of removed reader to fail")
+	}
+}
+
+func TestManager_UpdateConnLimits(t *testing.T) {
+	const limit = 1024
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	manager := NewManager()
+	conn := NewRateLimitedConn(local, limit, limit)
+	manager.AddConn("conn", conn)
+
+	if !manager.UpdateWriteLimit("conn", limit/2) {
+		t.Fatalf("expected registered conn to be updated")
+	}
+	if conn.writeLimiter.Limit() != limit/2 {
+		t.Fatalf("unexpected write limit: %d expected: %d", conn.writeLimiter.Limit(), limit/2)
+	}
+	if conn.readLimiter.Limit() != limit {
+		t.Fatalf("read limit changed by write update: %d expected: %d", conn.readLimiter.Limit(), limit)
+	}
+
+	if !manager.UpdateReadLimit("conn", limit*2) {
+		t.Fatalf("expected registered conn to be updated")
+	}
+	if conn.readLimiter.Limit() != limit*2 {
+		t.Fatalf("unexpected read limit: %d expected: %d", conn.readLimiter.Limit(), limit*2)
+	}
+
+	if manager.UpdateReadLimit("missing", limit) || manager.UpdateWriteLimit("missing", limit) {
+		t.Fatalf("expected update of unregistered conn to fail")
+	}
+}
+
+func TestManager_DumpJSON(t *testing.T) {
+	const limit = 1024
+
+	manager := NewManager()
+	manager.AddReader("a", NewRateLimitedReader(bytes.NewReader(nil), limit))
+	manager.AddReader("b", NewRateLimitedReader(bytes.NewReader(nil), limit*2))
+
+	dump, err := manager.DumpJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var stats map[string]map[string]any
+	if err := json.Unmarshal(dump, &stats); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", dump, err)
+	}
+	if len(stats) != 2 || stats["a"]["limit"] != float64(limit) || stats["b"]["limit"] != float64(limit*2) {
+		t.Fatalf("unexpected dump: %s", dump)
+	}
+	if _, ok := stats["a"]["throttled_for_ns"]; !ok {
+		t.Fatalf("missing throttled_for_ns in dump: %s", dump)
+	}
+}
+
+func TestManager_ScaleAll(t *testing.T) {
+	const limit = 1024
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	manager := NewManager()
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	unlimitedReader := NewRateLimitedReader(bytes.NewReader(nil), 0)
+	conn := NewRateLimitedConn(local, limit, limit*2)
+	manager.AddReader("reader", ratelimitedReader)
+	manager.AddReader("unlimited", unlimitedReader)
+	manager.AddConn("conn", conn)
+
+	if err := manager.ScaleAll(0.5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ratelimitedReader.Limiter().Limit() != limit/2 || unlimitedReader.Limiter().Limit() != 0 {
+		t.Fatalf("unexpected scaled limits: %d, %d expected: %d, 0", ratelimitedReader.Limiter().Limit(), unlimitedReader.Limiter().Limit(), limit/2)
+	}
+	if conn.readLimiter.Limit() != limit/2 || conn.writeLimiter.Limit() != limit {
+		t.Fatalf("unexpected scaled conn limits: %d, %d expected: %d, %d", conn.readLimiter.Limit(), conn.writeLimiter.Limit(), limit/2, limit)
+	}
+
+	// updates and new registrations are scaled too
+	manager.UpdateLimit("reader", limit*4)
+	added := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	manager.AddReader("added", added)
+	if ratelimitedReader.Limiter().Limit() != limit*2 || added.Limiter().Limit() != limit/2 {
+		t.Fatalf("unexpected limits while scaled: %d, %d expected: %d, %d", ratelimitedReader.Limiter().Limit(), added.Limiter().Limit(), limit*2, limit/2)
+	}
+
+	// scaling doesn't compound
+	manager.ScaleAll(1)
+	if ratelimitedReader.Limiter().Limit() != limit*4 || conn.writeLimiter.Limit() != limit*2 {
+		t.Fatalf("unexpected restored limits: %d, %d expected: %d, %d", ratelimitedReader.Limiter().Limit(), conn.writeLimiter.Limit(), limit*4, limit*2)
+	}
+
+	if err := manager.ScaleAll(0); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestManager_ScaleAllSharedLimiter(t *testing.T) {
+	const limit = 1000
+
+	manager := NewManager()
+	if err := manager.ScaleAll(0.5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// readers sharing a limiter scale it once, whatever the order
+	limiter := NewLimiter(limit)
+	manager.AddReader("first", NewReaderWithLimiter(bytes.NewReader(nil), limiter))
+	manager.AddReader("second", NewReaderWithLimiter(bytes.NewReader(nil), limiter))
+	if limiter.Limit() != limit/2 {
+		t.Fatalf("unexpected shared limit: %d expected: %d", limiter.Limit(), limit/2)
+	}
+
+	manager.ScaleAll(0.25)
+	if limiter.Limit() != limit/4 {
+		t.Fatalf("unexpected rescaled shared limit: %d expected: %d", limiter.Limit(), limit/4)
+	}
+
+	manager.ScaleAll(1)
+	if limiter.Limit() != limit {
+		t.Fatalf("unexpected restored shared limit: %d expected: %d", limiter.Limit(), limit)
+	}
+
+	// the limit is kept while a reader still draws from the limiter
+	manager.Remove("first")
+	manager.ScaleAll(0.5)
+	if limiter.Limit() != limit/2 {
+		t.Fatalf("unexpected shared limit after remove: %d expected: %d", limiter.Limit(), limit/2)
+	}
+}
+
+func TestManager_ScaleHandler(t *testing.T) {
+	const limit = 1024
+
+	manager := NewManager()
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	manager.AddReader("reader", ratelimitedReader)
+	server := httptest.NewServer(manager.ScaleHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"?factor=0.25", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "0.25" {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, body)
+	}
+	if ratelimitedReader.Limiter().Limit() != limit/4 {
+		t.Fatalf("unexpected limit: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit/4)
+	}
+
+	resp, err = http.Post(server.URL+"?factor=-1", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || manager.Scale() != 0.25 {
+		t.Fatalf("unexpected response to an invalid factor: %d scale: %v", resp.StatusCode, manager.Scale())
+	}
+}
+
+func TestManager_FairnessReport(t *testing.T) {
+	const limit = 1024
+	const dataSize = limit / 2
+
+	manager := NewManager()
+	shared := NewLimiter(limit * 4)
+	readers := map[string]*RateLimitedReader{
+		"a1": NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit),
+		"a2": NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit),
+		"b":  NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit*2),
+		"c1": NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), shared),
+		"c2": NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), shared),
+	}
+	for name, reader := range readers {
+		manager.AddReader(name, reader)
+	}
+	manager.SetTenant("a1", "acme")
+	manager.SetTenant("a2", "acme")
+	manager.SetTenant("c1", "globex")
+	manager.SetTenant("c2", "globex")
+
+	for _, reader := range readers {
+		read(t, reader, dataSize, dataSize)
+	}
+
+	// the report tells the window actually measured
+	for _, tc := range []struct{ window, measured time.Duration }{
+		{100 * time.Millisecond, time.Second},
+		{1500 * time.Millisecond, 2 * time.Second},
+		{time.Hour, MaxFairnessWindow},
+	} {
+		if window := manager.FairnessReport(tc.window).Window; window != tc.measured {
+			t.Fatalf("unexpected window for %v: %v expected: %v", tc.window, window, tc.measured)
+		}
+	}
+
+	report := manager.FairnessReport(time.Hour)
+
+	type share struct {
+		name                string
+		limit               int64
+		allocated, consumed float64
+	}
+	assertShares := func(kind string, got []Fairness, expected []share) {
+		t.Helper()
+		if len(got) != len(expected) {
+			t.Fatalf("unexpected %s: %+v", kind, got)
+		}
+		for i, e := range expected {
+			f := got[i]
+			if f.Name != e.name || f.Limit != e.limit || f.Throughput == 0 ||
+				math.Abs(f.AllocatedShare-e.allocated) > 1e-9 || math.Abs(f.ConsumedShare-e.consumed) > 1e-9 {
+				t.Fatalf("unexpected %s line: %+v expected: %+v", kind, f, e)
+			}
+		}
+	}
+
+	// the shared limiter is allocated once, split between its readers
+	assertShares("readers", report.Readers, []share{
+		{"a1", limit, 0.125, 0.2},
+		{"a2", limit, 0.125, 0.2},
+		{"b", limit * 2, 0.25, 0.2},
+		{"c1", limit * 4, 0.25, 0.2},
+		{"c2", limit * 4, 0.25, 0.2},
+	})
+	assertShares("tenants", report.Tenants, []share{
+		{"acme", limit * 2, 0.25, 0.4},
+		{"b", limit * 2, 0.25, 0.2},
+		{"globex", limit * 4, 0.5, 0.4},
+	})
+}
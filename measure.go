@@ -0,0 +1,61 @@
+package ratelimitedreader
+
+import (
+	"runtime"
+	"time"
+)
+
+// Overhead is what MeasureOverhead observed over a synthetic transfer.
+type Overhead struct {
+	Bytes   int64
+	Elapsed time.Duration
+
+	// Throughput is the achieved rate in bytes per second, to compare with
+	// the limit asked for.
+	Throughput float64
+
+	// Wakeups counts the times the reader slept to keep to the limit, and
+	// Allocs the heap allocations made while reading.
+	Wakeups int64
+	Allocs  uint64
+}
+
+// MeasureOverhead reads from an endless in-memory source at limit bytes per
+// second, bufSize bytes per Read, for about duration, so users can check the
+// pacing meets their precision needs on their own hardware. It takes at least
+// duration to return. opts configure the reader, e.g. with WithInterval, to
+// compare settings.
+func MeasureOverhead(bufSize int, limit int64, duration time.Duration, opts ...Option) Overhead {
+	r := NewRateLimitedReader(zeroReader{}, limit, opts...)
+	defer r.Close()
+	buf := make([]byte, bufSize)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var total int64
+	start := time.Now()
+	for time.Since(start) < duration {
+		n, _ := r.Read(buf)
+		total += int64(n)
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	return Overhead{
+		Bytes:      total,
+		Elapsed:    elapsed,
+		Throughput: float64(total) / elapsed.Seconds(),
+		Wakeups:    r.wakeups.Load(),
+		Allocs:     after.Mallocs - before.Mallocs,
+	}
+}
+
+// zeroReader is an endless source of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
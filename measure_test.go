@@ -0,0 +1,23 @@
+package ratelimitedreader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasureOverhead(t *testing.T) {
+	const bufferSize = 1024
+	const limit = 20 * 1024
+	const duration = time.Second
+
+	overhead := MeasureOverhead(bufferSize, limit, duration)
+	if overhead.Elapsed < duration {
+		t.Fatalf("measured for too short: %v expected at least: %v", overhead.Elapsed, duration)
+	}
+	if overhead.Throughput < limit*0.8 || overhead.Throughput > limit*1.2 {
+		t.Fatalf("unexpected throughput: %.0f expected about: %d", overhead.Throughput, limit)
+	}
+	if overhead.Wakeups == 0 {
+		t.Fatalf("expected the reader to sleep while measuring")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build tinygo || ratelimitedreader_minimal
+
+// The minimal build, set by TinyGo or the ratelimitedreader_minimal build tag,
+// leaves out everything that runs goroutines in the background, such as
+// read-ahead, idle closing, min rate alerts, usage reporting, the Downloader
+// and the Uploader, and everything that depends on net or net/http, gzip or
+// encoding/json, such as conns, listeners, the HTTP handler and transport,
+// the multipart helpers, Pool and Manager. What's left is the limiter, the
+// reader and writer and the features built on them, for embedded targets
+// like field gateways capping radio or cellular usage.
+
+package ratelimitedreader
+
+// readAhead, minRateAlert and usage are never set in the minimal build, these only
+// let the reader build.
+type readAhead struct{}
+
+func (ra *readAhead) read(r *RateLimitedReader, p []byte, block bool) (int, error) {
+	return r.read(p)
+}
+
+func (ra *readAhead) occupancy() (buffered, size int) {
+	return 0, 0
+}
+
+func (ra *readAhead) close() {}
+
+type minRateAlert struct{}
+
+func (a *minRateAlert) stop() {}
+
+type usageTap struct{}
+
+func (t *usageTap) add(n int64) {}
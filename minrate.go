@@ -0,0 +1,59 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithMinRateAlert calls fn once delivered throughput stays under minRate
+// for period even though the limit allows minRate, catching degraded
+// upstreams. Throughput is checked every second, until the underlying reader
+// reaches EOF or the reader is closed, from its own goroutine; fn gets the
+// throughput measured as CurrentThroughput, once per such stretch. period is
+// at least a second, the window throughput is measured over.
+func WithMinRateAlert(minRate int64, period time.Duration, fn func(throughput int64)) Option {
+	return func(r *RateLimitedReader) {
+		alert := &minRateAlert{
+			minRate: minRate,
+			stretch: rateStretch{after: max(period, throughputWindow)},
+			fn:      fn,
+		}
+		alert.timer = time.AfterFunc(throughputWindow, func() { alert.check(r) })
+		r.minRateAlert = alert
+	}
+}
+
+// minRateAlert checks throughput against minRate every second.
+type minRateAlert struct {
+	minRate int64
+	stretch rateStretch
+	fn      func(throughput int64)
+	timer   *time.Timer
+	closed  atomic.Bool
+}
+
+// check observes throughput once the transfer started, and rearms the timer
+// unless it ended.
+func (a *minRateAlert) check(r *RateLimitedReader) {
+	if a.closed.Load() || r.eof.Load() || r.stopped() {
+		return
+	}
+
+	if r.startedAt.Load() != 0 {
+		now := time.Now()
+		throughput := r.throughput.rate(now)
+		limit := r.limiter.Limit()
+		below := throughput < a.minRate && (limit <= 0 || limit >= a.minRate)
+		if a.stretch.observe(now, below) {
+			a.fn(throughput)
+		}
+	}
+	a.timer.Reset(throughputWindow)
+}
+
+func (a *minRateAlert) stop() {
+	a.closed.Store(true)
+	a.timer.Stop()
+}
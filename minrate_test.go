@@ -0,0 +1,50 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_MinRateAlert(t *testing.T) {
+	const limit = 20 * 1024
+	const sourceRate = limit / 4 // a degraded upstream
+	const minRate = limit / 2
+	const bufferSize = sourceRate / 20
+
+	alerts := make(chan int64, 1)
+	ratelimitedReader := NewRateLimitedReader(slowReader{rate: sourceRate}, limit,
+		WithMinRateAlert(minRate, time.Second, func(throughput int64) { alerts <- throughput }))
+	defer ratelimitedReader.Close()
+
+	buf := make([]byte, bufferSize)
+	for start := time.Now(); time.Since(start) < 2500*time.Millisecond; {
+		ratelimitedReader.Read(buf)
+	}
+
+	select {
+	case throughput := <-alerts:
+		if throughput >= minRate {
+			t.Fatalf("unexpected throughput alerted: %d min rate: %d", throughput, minRate)
+		}
+	default:
+		t.Fatalf("expected an alert for throughput below %d", minRate)
+	}
+}
+
+func TestRateLimitedReader_MinRateAlertLimitBelow(t *testing.T) {
+	const dataSize = 20 * 1024
+	const bufferSize = 1024
+	const limit = dataSize / 2
+	const minRate = limit * 2 // the limit can't allow it, so no alert
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit,
+		WithMinRateAlert(minRate, time.Second, func(throughput int64) {
+			t.Errorf("unexpected alert while the limit is below the min rate, throughput: %d", throughput)
+		}))
+	defer ratelimitedReader.Close()
+
+	read(t, ratelimitedReader, bufferSize, dataSize)
+}
@@ -0,0 +1,103 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// MultipartReader hands out the parts of a multipart.Reader as readers
+// drawing from one limiter, so a form upload is paced as a unit however its
+// parts are read. Headers and boundaries are read unpaced.
+type MultipartReader struct {
+	reader  *multipart.Reader
+	limiter *Limiter
+	opts    []Option
+}
+
+// RateLimitedPart is a part of a MultipartReader, read at the limiter's
+// rate. Closing it closes the part.
+type RateLimitedPart struct {
+	*RateLimitedReader
+
+	// Part is the underlying part, for its Header, FormName and FileName.
+	// Reading it directly skips the pacing.
+	Part *multipart.Part
+}
+
+// NewMultipartReader returns a MultipartReader pacing every part of reader
+// against limiter, with opts applied to each part's reader.
+func NewMultipartReader(reader *multipart.Reader, limiter *Limiter, opts ...Option) *MultipartReader {
+	return &MultipartReader{reader: reader, limiter: limiter, opts: opts}
+}
+
+// NextPart returns the next part, see multipart.Reader.NextPart.
+func (m *MultipartReader) NextPart() (*RateLimitedPart, error) {
+	return m.wrap(m.reader.NextPart())
+}
+
+// NextRawPart returns the next part without decoding quoted-printable
+// bodies, see multipart.Reader.NextRawPart.
+func (m *MultipartReader) NextRawPart() (*RateLimitedPart, error) {
+	return m.wrap(m.reader.NextRawPart())
+}
+
+func (m *MultipartReader) wrap(part *multipart.Part, err error) (*RateLimitedPart, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimitedPart{
+		RateLimitedReader: NewReadCloserWithLimiter(part, m.limiter, m.opts...),
+		Part:              part,
+	}, nil
+}
+
+// MultipartWriter is a multipart.Writer whose parts are written through
+// writers drawing from one limiter, so a form download or upload is paced as
+// a unit across its parts. Headers and boundaries are written unpaced.
+type MultipartWriter struct {
+	*multipart.Writer
+	limiter *Limiter
+}
+
+// NewMultipartWriter returns a MultipartWriter pacing every part written to
+// writer against limiter.
+func NewMultipartWriter(writer *multipart.Writer, limiter *Limiter) *MultipartWriter {
+	return &MultipartWriter{Writer: writer, limiter: limiter}
+}
+
+// CreatePart creates a part with header, see multipart.Writer.CreatePart.
+func (m *MultipartWriter) CreatePart(header textproto.MIMEHeader) (*RateLimitedWriter, error) {
+	return m.wrap(m.Writer.CreatePart(header))
+}
+
+// CreateFormFile creates a file part, see multipart.Writer.CreateFormFile.
+func (m *MultipartWriter) CreateFormFile(fieldname, filename string) (*RateLimitedWriter, error) {
+	return m.wrap(m.Writer.CreateFormFile(fieldname, filename))
+}
+
+// CreateFormField creates a field part, see
+// multipart.Writer.CreateFormField.
+func (m *MultipartWriter) CreateFormField(fieldname string) (*RateLimitedWriter, error) {
+	return m.wrap(m.Writer.CreateFormField(fieldname))
+}
+
+// WriteField creates a field part and writes value to it at the limited
+// rate, see multipart.Writer.WriteField.
+func (m *MultipartWriter) WriteField(fieldname, value string) error {
+	part, err := m.CreateFormField(fieldname)
+	if err != nil {
+		return err
+	}
+	_, err = part.WriteString(value)
+	return err
+}
+
+func (m *MultipartWriter) wrap(part io.Writer, err error) (*RateLimitedWriter, error) {
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterWithLimiter(part, m.limiter), nil
+}
@@ -0,0 +1,89 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"testing"
+	"time"
+)
+
+func TestMultipartReader(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, name := range []string{"first", "second"} {
+		part, err := writer.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatalf("unexpected error creating part: %v", err)
+		}
+		part.Write(make([]byte, dataSize/partsAmount))
+	}
+	writer.Close()
+
+	limiter := NewLimiter(limit)
+	reader := NewMultipartReader(multipart.NewReader(&body, writer.Boundary()), limiter)
+
+	start := time.Now()
+	var names []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for next part: %v", err)
+		}
+		if part.Limiter() != limiter {
+			t.Fatalf("expected part to draw from the shared limiter")
+		}
+
+		read(t, part.RateLimitedReader, dataSize, dataSize/partsAmount)
+		names = append(names, part.Part.FormName())
+		part.Close()
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	if len(names) != partsAmount || names[0] != "first" || names[1] != "second" {
+		t.Fatalf("unexpected parts: %v", names)
+	}
+}
+
+func TestMultipartWriter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var body bytes.Buffer
+	writer := NewMultipartWriter(multipart.NewWriter(&body), NewLimiter(limit))
+
+	start := time.Now()
+	part, err := writer.CreateFormFile("file", "file.bin")
+	if err != nil {
+		t.Fatalf("unexpected error creating part: %v", err)
+	}
+	if n, err := part.Write(make([]byte, dataSize/partsAmount)); err != nil || n != dataSize/partsAmount {
+		t.Fatalf("unexpected write, wrote: %d error: %v", n, err)
+	}
+	if err := writer.WriteField("field", string(make([]byte, dataSize/partsAmount))); err != nil {
+		t.Fatalf("unexpected error writing field: %v", err)
+	}
+	writer.Close()
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(dataSize * 2)
+	if err != nil {
+		t.Fatalf("unexpected error reading form: %v", err)
+	}
+	if len(form.File["file"]) != 1 || form.File["file"][0].Size != dataSize/partsAmount {
+		t.Fatalf("unexpected file parts: %v", form.File["file"])
+	}
+	if len(form.Value["field"]) != 1 || len(form.Value["field"][0]) != dataSize/partsAmount {
+		t.Fatalf("unexpected field parts: %d", len(form.Value["field"]))
+	}
+}
@@ -0,0 +1,198 @@
+package ratelimitedreader
+
+import "io"
+
+// defaultMuxChunkSize is how much a Mux reads from a stream at once by
+// default.
+const defaultMuxChunkSize = 32 * 1024
+
+// MuxStream is a reader for a Mux to interleave, with its share: a stream of
+// weight 2 gets twice the chunks of a stream of weight 1 while both have data.
+type MuxStream struct {
+	Reader io.Reader
+	Weight int
+}
+
+// MuxOption configures a Mux.
+type MuxOption func(m *Mux)
+
+// WithMuxChunkSize reads streams size bytes at a time, the most one stream
+// writes before the next gets a turn.
+func WithMuxChunkSize(size int) MuxOption {
+	return func(m *Mux) {
+		if size > 0 {
+			m.chunkSize = size
+		}
+	}
+}
+
+// WithMuxFrame writes each chunk through frame, which gets the index of the
+// stream it came from, so the destination can tell the streams apart, e.g.
+// by writing a header before the chunk. By default chunks are written as is.
+func WithMuxFrame(frame func(dst io.Writer, stream int, chunk []byte) error) MuxOption {
+	return func(m *Mux) {
+		m.frame = frame
+	}
+}
+
+// Mux interleaves several streams, typically rate-limited readers, into one
+// destination in weighted round robin, so one conn can carry several
+// throttled sub-streams fairly: a stream held back by its limit doesn't hold
+// back the others. It's an io.WriterTo.
+type Mux struct {
+	streams   []MuxStream
+	chunkSize int
+	frame     func(dst io.Writer, stream int, chunk []byte) error
+}
+
+// NewMux returns a Mux interleaving streams, weights below 1 counting as 1.
+func NewMux(streams []MuxStream, opts ...MuxOption) *Mux {
+	m := &Mux{
+		streams:   streams,
+		chunkSize: defaultMuxChunkSize,
+		frame:     writeChunk,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// muxChunk is a chunk read from the stream-th stream, err its read's error.
+type muxChunk struct {
+	stream int
+	data   []byte
+	err    error
+}
+
+// WriteTo writes the streams' data to dst as it comes, until every stream
+// reached EOF or any failed, returning the bytes of data written. Each
+// stream is read on its own goroutine, at most its weight in chunks ahead
+// of dst. On a failure of dst or a stream, WriteTo closes the streams that
+// are io.Closers and returns without waiting for reads in progress, whose
+// goroutines end once those reads return.
+func (m *Mux) WriteTo(dst io.Writer) (n int64, err error) {
+	chunks := make(chan muxChunk)
+	done := make(chan struct{})
+	credits := make([]chan struct{}, len(m.streams))
+	for i, stream := range m.streams {
+		credits[i] = make(chan struct{}, max(stream.Weight, 1))
+		for range cap(credits[i]) {
+			credits[i] <- struct{}{}
+		}
+	}
+
+	for i, stream := range m.streams {
+		go m.read(i, stream.Reader, credits[i], chunks, done)
+	}
+	defer func() {
+		close(done)
+		if err != nil {
+			m.closeStreams()
+		}
+	}()
+
+	pending := make([][]muxChunk, len(m.streams))
+	current := make([]int, len(m.streams))
+	live := len(m.streams)
+	queued := 0
+	for live > 0 || queued > 0 {
+		if queued == 0 {
+			chunk := <-chunks
+			pending[chunk.stream] = append(pending[chunk.stream], chunk)
+			queued++
+		}
+		// take whatever else is ready, so the pick weighs it too
+		for drained := false; !drained; {
+			select {
+			case chunk := <-chunks:
+				pending[chunk.stream] = append(pending[chunk.stream], chunk)
+				queued++
+			default:
+				drained = true
+			}
+		}
+
+		i := m.pick(pending, current)
+		chunk := pending[i][0]
+		pending[i] = pending[i][1:]
+		queued--
+
+		if len(chunk.data) > 0 {
+			if err := m.frame(dst, i, chunk.data); err != nil {
+				return n, err
+			}
+			n += int64(len(chunk.data))
+		}
+		credits[i] <- struct{}{}
+
+		if chunk.err == io.EOF {
+			live--
+		} else if chunk.err != nil {
+			return n, chunk.err
+		}
+	}
+
+	return n, nil
+}
+
+// read sends stream's chunks to chunks until it fails or reaches EOF, or
+// done closes, one per credit taken.
+func (m *Mux) read(i int, reader io.Reader, credits <-chan struct{}, chunks chan<- muxChunk, done <-chan struct{}) {
+	for {
+		select {
+		case <-credits:
+		case <-done:
+			return
+		}
+
+		buf := make([]byte, m.chunkSize)
+		n, err := reader.Read(buf)
+		select {
+		case chunks <- muxChunk{stream: i, data: buf[:n], err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// closeStreams closes the streams that are io.Closers, cutting short reads
+// in progress.
+func (m *Mux) closeStreams() {
+	for _, stream := range m.streams {
+		if closer, ok := stream.Reader.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// pick returns the stream to write next among those with pending chunks, by
+// smooth weighted round robin over current, the streams' running credit.
+func (m *Mux) pick(pending [][]muxChunk, current []int) int {
+	best, total := -1, 0
+	for i, chunks := range pending {
+		if len(chunks) == 0 {
+			continue
+		}
+		weight := max(m.streams[i].Weight, 1)
+		current[i] += weight
+		total += weight
+		if best < 0 || current[i] > current[best] {
+			best = i
+		}
+	}
+	current[best] -= total
+	return best
+}
+
+// writeChunk writes chunk to dst as is.
+func writeChunk(dst io.Writer, stream int, chunk []byte) error {
+	n, err := dst.Write(chunk)
+	if err == nil && n != len(chunk) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestMux(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	// each stream is held back by its own limit, not by the other's
+	mux := NewMux([]MuxStream{
+		{Reader: NewRateLimitedReader(bytes.NewReader(bytes.Repeat([]byte{'a'}, dataSize)), limit), Weight: 1},
+		{Reader: NewRateLimitedReader(bytes.NewReader(bytes.Repeat([]byte{'b'}, dataSize)), limit), Weight: 1},
+	})
+
+	var dst bytes.Buffer
+	start := time.Now()
+	n, err := mux.WriteTo(&dst)
+	if err != nil || n != 2*dataSize {
+		t.Fatalf("unexpected write, written: %d error: %v", n, err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	if a := bytes.Count(dst.Bytes(), []byte{'a'}); a != dataSize {
+		t.Fatalf("unexpected stream bytes: %d expected: %d", a, dataSize)
+	}
+}
+
+func TestMux_Weights(t *testing.T) {
+	const dataSize = 64 * 1024 // 64KB
+	const chunkSize = 1024
+
+	// both streams are backlogged behind a slow destination, so the
+	// interleaving follows their weights alone
+	var streams []int
+	frame := func(dst io.Writer, stream int, chunk []byte) error {
+		time.Sleep(time.Millisecond)
+		streams = append(streams, stream)
+		return nil
+	}
+
+	mux := NewMux([]MuxStream{
+		{Reader: bytes.NewReader(make([]byte, dataSize)), Weight: 3},
+		{Reader: bytes.NewReader(make([]byte, dataSize)), Weight: 1},
+	}, WithMuxChunkSize(chunkSize), WithMuxFrame(frame))
+
+	if _, err := mux.WriteTo(io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// while both have data, the first stream gets 3 of every 4 turns
+	var counts [2]int
+	for _, stream := range streams[:20] {
+		counts[stream]++
+	}
+	if counts[0] < 13 || counts[0] > 17 {
+		t.Fatalf("unexpected interleaving: %v expected about 15 of 20 turns for weight 3", streams[:20])
+	}
+}
+
+func TestMux_FailureDoesntWaitOnStuckReads(t *testing.T) {
+	// two streams that never deliver, one of them closable, and a failing one
+	closable, closableWriter := io.Pipe()
+	stuck, stuckWriter := io.Pipe()
+	defer stuckWriter.Close()
+
+	mux := NewMux([]MuxStream{
+		{Reader: closable},
+		{Reader: struct{ io.Reader }{stuck}},
+		{Reader: iotest.ErrReader(errTransient)},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mux.WriteTo(io.Discard)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errTransient) {
+			t.Fatalf("unexpected error: %v expected: %v", err, errTransient)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("WriteTo kept waiting on stuck reads after a failure")
+	}
+
+	if _, err := closableWriter.Write([]byte{0}); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("unexpected error writing to a closed stream: %v expected: %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,34 @@
+package ratelimitedreader
+
+// Pacing is where a reader sleeps relative to its underlying reads, which
+// trades strictness of the cap against latency and delivery on slow sources.
+type Pacing int
+
+const (
+	// PaceBeforeRead sleeps for a grant before reading it, the default. The
+	// limit is never exceeded, not even briefly, which suits strict caps, but
+	// a read delivering less than its grant still pays for all of it, so slow
+	// or bursty sources under-deliver.
+	PaceBeforeRead Pacing = iota
+
+	// PaceAfterRead reads first and then sleeps for what was actually read,
+	// so reads are only charged for bytes the source delivered and data is
+	// handed over as soon as it arrives, which suits latency-sensitive
+	// consumers. The cost is a burst of up to a grant above the limit at the
+	// start of a read, and Read returning only after the sleep.
+	PaceAfterRead
+
+	// PaceProportional sleeps for a grant before reading it like
+	// PaceBeforeRead, then credits back what the source didn't deliver
+	// toward the next grants. The cap stays strict while short reads are
+	// charged in proportion to what they delivered, at the cost of the credit
+	// letting a later grant come early.
+	PaceProportional
+)
+
+// WithPacing sets where the reader sleeps, see Pacing.
+func WithPacing(pacing Pacing) Option {
+	return func(r *RateLimitedReader) {
+		r.pacing = pacing
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestRateLimitedReader_PaceBeforeRead(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// every underlying read delivers half its grant, yet pays for all of it
+	reader := iotest.HalfReader(bytes.NewReader(make([]byte, dataSize)))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithPacing(PaceBeforeRead))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+}
+
+func TestRateLimitedReader_PaceAfterRead(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// every underlying read delivers half its grant
+	reader := iotest.HalfReader(bytes.NewReader(make([]byte, dataSize)))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithPacing(PaceAfterRead))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedReader_PaceProportional(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// every underlying read delivers half its grant
+	reader := iotest.HalfReader(bytes.NewReader(make([]byte, dataSize)))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithPacing(PaceProportional))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedReader_PacingKeepsLimit(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	for _, pacing := range []Pacing{PaceBeforeRead, PaceAfterRead, PaceProportional} {
+		reader := bytes.NewReader(make([]byte, dataSize*2))
+		ratelimitedReader := NewRateLimitedReader(reader, limit, WithPacing(pacing))
+
+		start := time.Now()
+		read(t, ratelimitedReader, bufferSize, dataSize*2)
+		assertReadTimes(t, time.Since(start), partsAmount*2, partsAmount*2+1)
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimitedreader
+
+import "sync"
+
+// hold holds reads while paused, see Pause.
+type hold struct {
+	mu sync.Mutex
+
+	// resumed is closed on Resume, nil while not paused
+	resumed chan struct{}
+}
+
+// Pause holds reading until Resume: blocking reads wait for it, while
+// non-blocking ones, TryRead and reads in poll mode, return ErrPaused. A read
+// already under way finishes first. Close ends the pause.
+func (r *RateLimitedReader) Pause() {
+	r.hold.mu.Lock()
+	defer r.hold.mu.Unlock()
+
+	if r.hold.resumed == nil {
+		r.hold.resumed = make(chan struct{})
+	}
+}
+
+// Resume lets reads held by Pause go on.
+func (r *RateLimitedReader) Resume() {
+	r.hold.mu.Lock()
+	defer r.hold.mu.Unlock()
+
+	if r.hold.resumed != nil {
+		close(r.hold.resumed)
+		r.hold.resumed = nil
+	}
+}
+
+// Paused reports whether reading is paused.
+func (r *RateLimitedReader) Paused() bool {
+	r.hold.mu.Lock()
+	defer r.hold.mu.Unlock()
+
+	return r.hold.resumed != nil
+}
+
+// waitResumed waits while reading is paused, returning ErrClosed if closed
+// or stopped first.
+func (r *RateLimitedReader) waitResumed() error {
+	r.hold.mu.Lock()
+	resumed := r.hold.resumed
+	r.hold.mu.Unlock()
+
+	if resumed == nil {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+	case <-r.stopC:
+		return ErrClosed
+	case <-r.readDone():
+		return ErrClosed
+	}
+	if r.stopped() {
+		return ErrClosed
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Pause(t *testing.T) {
+	const dataSize = 1024
+	const limit = dataSize * 10
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit)
+
+	ratelimitedReader.Pause()
+	if !ratelimitedReader.Paused() {
+		t.Fatalf("expected reader to be paused")
+	}
+	if _, err := ratelimitedReader.TryRead(make([]byte, dataSize)); !errors.Is(err, ErrPaused) {
+		t.Fatalf("unexpected error for a non-blocking read: %v expected: %v", err, ErrPaused)
+	}
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		ratelimitedReader.Resume()
+	}()
+
+	start := time.Now()
+	read(t, ratelimitedReader, dataSize, dataSize)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("read while paused, took: %v", elapsed)
+	}
+	if ratelimitedReader.Paused() {
+		t.Fatalf("expected reader to be resumed")
+	}
+}
+
+func TestRateLimitedReader_CloseWhilePaused(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReadCloser(io.NopCloser(bytes.NewReader(make([]byte, limit))), limit)
+	ratelimitedReader.Pause()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ratelimitedReader.Close()
+	}()
+
+	start := time.Now()
+	if _, err := ratelimitedReader.Read(make([]byte, limit)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrClosed)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
@@ -0,0 +1,12 @@
+package ratelimitedreader
+
+import "io"
+
+// ThrottledPipe returns a connected in-memory pipe whose data flows from the
+// writer to the reader at no more than limit bytes per second, handy for
+// testing consumers against a slow producer. As with io.Pipe, writes block
+// until the reader takes the data.
+func ThrottledPipe(limit int64, opts ...Option) (*RateLimitedReader, *io.PipeWriter) {
+	pr, pw := io.Pipe()
+	return NewRateLimitedReadCloser(pr, limit, opts...), pw
+}
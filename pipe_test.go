@@ -0,0 +1,44 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestThrottledPipe(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader, writer := ThrottledPipe(limit)
+
+	writeDone := make(chan time.Duration)
+	start := time.Now()
+	go func() {
+		writer.Write(make([]byte, dataSize))
+		writer.Close()
+		writeDone <- time.Since(start)
+	}()
+
+	read(t, reader, bufferSize, dataSize)
+	if _, err := reader.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("unexpected error after writer closed: %v expected: %v", err, io.EOF)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	// the writer is held back by the reader's pace
+	assertReadTimes(t, <-writeDone, partsAmount, partsAmount+1)
+}
+
+func TestThrottledPipe_CloseReader(t *testing.T) {
+	const limit = 1024
+
+	reader, writer := ThrottledPipe(limit)
+	reader.Close()
+
+	if _, err := writer.Write([]byte("data")); err != io.ErrClosedPipe {
+		t.Fatalf("unexpected error writing to a closed pipe: %v expected: %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// Pool hands out reset RateLimitedReaders and RateLimitedConns for servers
+// wrapping many short-lived streams, to spare the garbage collector. The zero
+// value is ready to use.
+//
+// Only put back readers and conns that are done with and no longer
+// referenced. Readers drawing from a shared Limiter are given their own one
+// again, and ones with read-ahead, an idle close or a min rate alert aren't
+// pooled as their filler or timer may still be running.
+type Pool struct {
+	readers sync.Pool
+	conns   sync.Pool
+}
+
+func (p *Pool) GetReader(reader io.Reader, limit int64, opts ...Option) *RateLimitedReader {
+	return p.GetReadCloser(io.NopCloser(reader), limit, opts...)
+}
+
+func (p *Pool) GetReadCloser(reader io.ReadCloser, limit int64, opts ...Option) *RateLimitedReader {
+	r, ok := p.readers.Get().(*RateLimitedReader)
+	if !ok {
+		return NewRateLimitedReadCloser(reader, limit, opts...)
+	}
+
+	r.init(reader, nil, limit, opts...)
+	return r
+}
+
+func (p *Pool) PutReader(r *RateLimitedReader) {
+	if r.readAhead != nil || r.idleTimer != nil || r.minRateAlert != nil {
+		return
+	}
+
+	r.reader = nil
+	p.readers.Put(r)
+}
+
+// GetConn returns a reset conn configured by opts only, so options such as
+// WithSyscallConn set on the conn it was before don't carry over.
+func (p *Pool) GetConn(conn net.Conn, readLimit, writeLimit int64, opts ...ConnOption) *RateLimitedConn {
+	c, ok := p.conns.Get().(*RateLimitedConn)
+	if !ok {
+		return NewRateLimitedConn(conn, readLimit, writeLimit, opts...)
+	}
+
+	c.Conn = conn
+	c.shared = false
+	c.syscallConn = false
+	c.activeTransfers.Store(0)
+	c.readLimiter.reset(readLimit)
+	if c.writeLimiter == c.readLimiter {
+		c.writeLimiter = NewLimiter(writeLimit)
+	} else {
+		c.writeLimiter.reset(writeLimit)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (p *Pool) PutConn(c *RateLimitedConn) {
+	c.Conn = nil
+	p.conns.Put(c)
+}
@@ -0,0 +1,109 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPool_ReaderReuse(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var pool Pool
+	first := pool.GetReader(bytes.NewReader(make([]byte, dataSize)), limit*2)
+	read(t, first, bufferSize, dataSize)
+	pool.PutReader(first)
+
+	key := "B"
+	second := pool.GetReader(strings.NewReader(strings.Repeat(key, dataSize)), limit)
+	if second.Limiter().Limit() != limit {
+		t.Fatalf("unexpected limit on pooled reader: %d expected: %d", second.Limiter().Limit(), limit)
+	}
+
+	start := time.Now()
+	data, _ := read(t, second, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+	if string(data) != strings.Repeat(key, dataSize) {
+		t.Fatalf("pooled reader read data of a previous reader")
+	}
+}
+
+func TestPool_ReaderResetState(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), 0)
+	shared := NewLimiter(limit)
+	ratelimitedReader.limiter = shared
+	ratelimitedReader.SetPollMode(true)
+	ratelimitedReader.throttledC <- struct{}{}
+
+	ratelimitedReader.init(io.NopCloser(bytes.NewReader(nil)), nil, limit*2)
+	if ratelimitedReader.Limiter() == shared {
+		t.Fatalf("expected reset reader to stop drawing from a shared limiter")
+	}
+	if ratelimitedReader.Limiter().Limit() != limit*2 {
+		t.Fatalf("unexpected limit after reset: %d expected: %d", ratelimitedReader.Limiter().Limit(), limit*2)
+	}
+	if ratelimitedReader.pollMode.Load() {
+		t.Fatalf("expected poll mode to be reset")
+	}
+	select {
+	case <-ratelimitedReader.Throttled():
+		t.Fatalf("expected pending throttled signal to be drained")
+	default:
+	}
+}
+
+func TestPool_ConnReuse(t *testing.T) {
+	const limit = 1024
+
+	var pool Pool
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	first := pool.GetConn(local, limit, limit)
+	pool.PutConn(first)
+
+	second := pool.GetConn(local, limit*2, limit*3)
+	defer second.Close()
+	if second.readLimiter.Limit() != limit*2 || second.writeLimiter.Limit() != limit*3 {
+		t.Fatalf("unexpected limits on pooled conn, read: %d write: %d expected: %d %d",
+			second.readLimiter.Limit(), second.writeLimiter.Limit(), limit*2, limit*3)
+	}
+	if second.Conn != local {
+		t.Fatalf("expected pooled conn to wrap the given conn")
+	}
+}
+
+func TestPool_ConnResetOptions(t *testing.T) {
+	const limit = 1024
+
+	var pool Pool
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	first := pool.GetConn(local, limit, limit, WithSyscallConn())
+	if !first.syscallConn {
+		t.Fatalf("expected conn options to be applied")
+	}
+	pool.PutConn(first)
+
+	second := pool.GetConn(local, limit, limit)
+	defer second.Close()
+	if second.syscallConn {
+		t.Fatalf("expected pooled conn not to keep syscall conn passthrough")
+	}
+
+	pool.PutConn(second)
+	if third := pool.GetConn(local, limit, limit, WithSyscallConn()); !third.syscallConn {
+		t.Fatalf("expected options to be applied to a pooled conn")
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimitedreader
+
+import "sync"
+
+// maxPriorityBypass is how many times a waiting grant may be passed over by
+// higher priority ones before it's served regardless.
+const maxPriorityBypass = 4
+
+// WithPriority gives the reader's grants priority on its limiter: while
+// readers sharing the limiter wait for budget, the waiting grant of the
+// highest priority is served first, ties first come first served. Once a
+// reader uses it, every reader of that limiter waits in turn, at priority 0
+// unless given another, and a grant passed over maxPriorityBypass times is
+// served next, so low priorities slow down but never starve. Suited to
+// "interactive beats batch" policies on one shared Limiter.
+func WithPriority(priority int) Option {
+	return func(r *RateLimitedReader) {
+		r.priority = priority
+		r.limiter.priorities.CompareAndSwap(nil, &priorityQueue{})
+	}
+}
+
+// priorityQueue hands out turns at booking a limiter's budget by priority.
+type priorityQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	waiters []*priorityWaiter
+}
+
+type priorityWaiter struct {
+	priority int
+	bypassed int
+	turn     chan struct{}
+}
+
+// acquire waits for a turn at priority.
+func (q *priorityQueue) acquire(priority int) {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return
+	}
+
+	w := &priorityWaiter{priority: priority, turn: make(chan struct{})}
+	q.waiters = append(q.waiters, w)
+	q.mu.Unlock()
+	<-w.turn
+}
+
+// release hands the turn to the next waiter: the oldest one passed over too
+// often, else the oldest of the highest priority.
+func (q *priorityQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiters) == 0 {
+		q.busy = false
+		return
+	}
+
+	next := 0
+	for i, w := range q.waiters {
+		if w.bypassed >= maxPriorityBypass {
+			next = i
+			break
+		}
+		if w.priority > q.waiters[next].priority {
+			next = i
+		}
+	}
+
+	// those queued before next are passed over
+	for _, w := range q.waiters[:next] {
+		w.bypassed++
+	}
+
+	w := q.waiters[next]
+	q.waiters = append(q.waiters[:next], q.waiters[next+1:]...)
+	close(w.turn)
+}
@@ -0,0 +1,75 @@
+package ratelimitedreader
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Priority(t *testing.T) {
+	const limit = 40 * 1024
+	const bufferSize = 1024
+	const lowAmount = 3
+	const duration = 1500 * time.Millisecond
+
+	limiter := NewLimiter(limit)
+	high := NewReaderWithLimiter(infiniteReader{}, limiter, WithPriority(1))
+	readers := []*RateLimitedReader{high}
+	for range lowAmount {
+		readers = append(readers, NewReaderWithLimiter(infiniteReader{}, limiter))
+	}
+
+	var wg sync.WaitGroup
+	totals := make([]atomic.Int64, len(readers))
+	for i, reader := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, bufferSize)
+			for start := time.Now(); time.Since(start) < duration; {
+				n, _ := reader.Read(buf)
+				totals[i].Add(int64(n))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	for i := range totals {
+		total += totals[i].Load()
+	}
+	// an equal share is a quarter, priority gets the high reader every
+	// other turn
+	if highTotal := totals[0].Load(); highTotal < total*2/5 {
+		t.Fatalf("high priority reader got %d of %d", highTotal, total)
+	}
+	for i := 1; i < len(totals); i++ {
+		if lowTotal := totals[i].Load(); lowTotal < total/20 {
+			t.Fatalf("low priority reader %d starved: %d of %d", i, lowTotal, total)
+		}
+	}
+}
+
+func TestPriorityQueue_StarvationProtection(t *testing.T) {
+	var q priorityQueue
+	q.acquire(0)
+
+	low := &priorityWaiter{priority: 0, bypassed: maxPriorityBypass, turn: make(chan struct{})}
+	high := &priorityWaiter{priority: 1, turn: make(chan struct{})}
+	q.waiters = []*priorityWaiter{low, high}
+
+	q.release()
+	select {
+	case <-low.turn:
+	default:
+		t.Fatalf("expected a grant passed over %d times to be served first", maxPriorityBypass)
+	}
+
+	q.release()
+	select {
+	case <-high.turn:
+	default:
+		t.Fatalf("expected the remaining waiter to be served")
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimitedreader
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Tier is a step of a tiered limit: Limit applies once After bytes of the
+// stream were read.
+type Tier struct {
+	After int64
+	Limit int64
+}
+
+// WithTiers steps the reader's limit through tiers as the stream crosses
+// their thresholds, as fair-use policies do, e.g.
+//
+//	WithTiers([]Tier{{After: 0, Limit: fast}, {After: 100 << 20, Limit: slow}})
+//
+// Until the first tier applies the reader runs at its own limit. Tiers pace
+// this reader only and never change its limiter: on a limiter shared with
+// other readers, such as through NewReaderWithLimiter or NewFromSame, a tier
+// can only lower the reader's rate below the shared limit.
+func WithTiers(tiers []Tier) Option {
+	return func(r *RateLimitedReader) {
+		r.tiers = slices.SortedStableFunc(slices.Values(tiers), func(a, b Tier) int {
+			return cmp.Compare(a.After, b.After)
+		})
+		r.nextTier = 0
+		r.tierLimit, r.tiered = 0, false
+		r.applyTiers()
+	}
+}
+
+// WithBoost runs the first boostBytes of the stream at boostLimit, then drops
+// to the reader's limit for the rest, like an ISP's speed boost. A boostLimit
+// of 0 or below runs the boost unlimited. The boost is the reader's own, like
+// tiers: on a limiter shared with other readers, including readers made with
+// NewFromSame, it can't lift the reader above the shared limit.
+func WithBoost(boostBytes, boostLimit int64) Option {
+	return func(r *RateLimitedReader) {
+		if boostBytes <= 0 {
+			return
+		}
+
+		r.boostBytes, r.boostLimit, r.boosting = boostBytes, boostLimit, true
+	}
+}
+
+// applyTiers applies the tiers the stream reached and ends the boost once
+// past it, returning how much is left to read until the next change, 0 when
+// there is none.
+func (r *RateLimitedReader) applyTiers() int64 {
+	if r.nextTier >= len(r.tiers) && !r.boosting {
+		return 0
+	}
+
+	transferred := r.totalRead.Load() + r.iterTotalRead.Load()
+	for r.nextTier < len(r.tiers) && r.tiers[r.nextTier].After <= transferred {
+		r.tierLimit, r.tiered = r.tiers[r.nextTier].Limit, true
+		r.nextTier++
+	}
+
+	var left int64
+	if r.nextTier < len(r.tiers) {
+		left = r.tiers[r.nextTier].After - transferred
+	}
+	if r.boosting {
+		boostLeft := r.boostBytes - transferred
+		if boostLeft <= 0 {
+			r.boosting = false
+		} else if left <= 0 || boostLeft < left {
+			left = boostLeft
+		}
+	}
+	return left
+}
+
+// tieredLimit returns limit, the limiter's limit per interval, with the
+// boost or the tier the stream reached applied. On the reader's own limiter
+// these replace it, on a shared one they can only lower it, so one reader
+// stepping through its tiers never speeds up the others.
+func (r *RateLimitedReader) tieredLimit(limit int64) int64 {
+	perSecond, ok := r.tierLimit, r.tiered
+	if r.boosting {
+		perSecond, ok = r.boostLimit, true
+	}
+	if !ok || r.limiter.Passthrough() {
+		return limit
+	}
+
+	var tierLimit int64
+	if perSecond > 0 {
+		tierLimit = max(mulDiv(perSecond, int64(r.limiter.Interval()), int64(time.Second)), 1)
+	}
+	if r.limiter == &r.ownLimiter {
+		return tierLimit
+	}
+	if tierLimit <= 0 {
+		return limit
+	}
+	if limit <= 0 {
+		return tierLimit
+	}
+	return min(limit, tierLimit)
+}
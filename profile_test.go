@@ -0,0 +1,130 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Boost(t *testing.T) {
+	const dataSize = 40 * 1024  // 40KB
+	const bufferSize = dataSize // one read call
+	const boostSize = dataSize / 2
+	const boostLimit = boostSize * 4 // a quarter second for the first half
+	const limit = dataSize / 2       // a second for the second half
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithBoost(boostSize, boostLimit))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+
+	if ratelimitedReader.boosting || ratelimitedReader.limiter.Limit() != limit {
+		t.Fatalf("unexpected limit after the boost: %d expected: %d", ratelimitedReader.limiter.Limit(), limit)
+	}
+}
+
+func TestRateLimitedReader_BoostSharedLimiter(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const limit = dataSize      // a second for the data of both readers
+	const boostLimit = limit * 4
+
+	limiter := NewLimiter(limit)
+	boosted := NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize/2)), limiter, WithBoost(dataSize, boostLimit))
+	other := NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize/2)), limiter)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, reader := range []*RateLimitedReader{boosted, other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(t, reader, bufferSize, dataSize/2)
+		}()
+	}
+	wg.Wait()
+	// the boost doesn't lift the shared limit
+	assertReadTimes(t, time.Since(start), 1, 1)
+
+	if limiter.Limit() != limit {
+		t.Fatalf("unexpected shared limit changed by the boost: %d expected: %d", limiter.Limit(), limit)
+	}
+
+	// nor do readers made from a boosted one restart the boost
+	own := NewRateLimitedReader(bytes.NewReader(nil), limit, WithBoost(dataSize, boostLimit))
+	sibling := NewFromSame(own, bytes.NewReader(make([]byte, dataSize)))
+	start = time.Now()
+	read(t, sibling, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
+
+func TestRateLimitedReader_UnlimitedBoost(t *testing.T) {
+	const dataSize = 40 * 1024  // 40KB
+	const bufferSize = dataSize // one read call
+	const boostSize = dataSize / 2
+	const limit = dataSize / 2 // a second for the second half
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithBoost(boostSize, 0))
+
+	start := time.Now()
+	read(t, ratelimitedReader, boostSize, boostSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+
+	read(t, ratelimitedReader, bufferSize, dataSize-boostSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
+
+func TestRateLimitedReader_Tiers(t *testing.T) {
+	const dataSize = 60 * 1024  // 60KB
+	const bufferSize = dataSize // one read call
+	const tierSize = dataSize / 3
+	const limit = tierSize // a second per tier at the reader's own limit
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithTiers([]Tier{
+		{After: tierSize * 2, Limit: tierSize / 2}, // two seconds for the last tier
+		{After: tierSize, Limit: tierSize * 4},     // a quarter second for the second tier
+	}))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 3, 4)
+
+	if ratelimitedReader.tierLimit != tierSize/2 {
+		t.Fatalf("unexpected limit after the last tier: %d expected: %d", ratelimitedReader.tierLimit, tierSize/2)
+	}
+	if ratelimitedReader.limiter.Limit() != limit {
+		t.Fatalf("unexpected limiter limit changed by tiers: %d expected: %d", ratelimitedReader.limiter.Limit(), limit)
+	}
+}
+
+func TestRateLimitedReader_TiersSharedLimiter(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const limit = dataSize      // a second for the whole data
+
+	limiter := NewLimiter(limit)
+	tiered := NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), limiter, WithTiers([]Tier{
+		{After: 0, Limit: limit * 4}, // can't lift the shared limit
+		{After: dataSize / 2, Limit: limit / 4},
+	}))
+
+	start := time.Now()
+	read(t, tiered, bufferSize, dataSize)
+	// half at the shared limit, half at a quarter of it
+	assertReadTimes(t, time.Since(start), 2, 3)
+
+	if limiter.Limit() != limit {
+		t.Fatalf("unexpected shared limit changed by tiers: %d expected: %d", limiter.Limit(), limit)
+	}
+
+	// the other readers on the limiter keep its limit
+	other := NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), limiter)
+	start = time.Now()
+	read(t, other, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
@@ -0,0 +1,51 @@
+package ratelimitedreader
+
+import "time"
+
+// quota caps how much a stream reads per window.
+type quota struct {
+	bytes  int64
+	window time.Duration
+	smooth bool
+
+	// start is when the current window started, zero before the first read
+	start time.Time
+	used  int64
+}
+
+// WithQuota caps the stream at quota bytes per window, e.g. 1TB a day, on top
+// of the reader's limit. Windows start with the first read, and once one's
+// quota is used up reads fail with ErrQuotaExceeded until the next starts.
+func WithQuota(quotaBytes int64, window time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		r.quota = &quota{bytes: quotaBytes, window: window}
+	}
+}
+
+// WithSmoothQuota is WithQuota also spreading the quota across the window
+// rather than front-loading it: reads are paced at no more than what's left
+// of the quota over what's left of the window, and at no more than the
+// reader's limit.
+func WithSmoothQuota(quotaBytes int64, window time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		r.quota = &quota{bytes: quotaBytes, window: window, smooth: true}
+	}
+}
+
+// left returns how much of the quota is left at now, starting a new window
+// once the current one is over.
+func (q *quota) left(now time.Time) int64 {
+	if q.start.IsZero() || !now.Before(q.start.Add(q.window)) {
+		q.start = now
+		q.used = 0
+	}
+	return q.bytes - q.used
+}
+
+// iterLimit returns the limit per read interval spreading what's left of the
+// quota over the rest of the window.
+func (q *quota) iterLimit(now time.Time, interval time.Duration) int64 {
+	left := q.left(now)
+	intervals := int64(q.start.Add(q.window).Sub(now) / interval)
+	return max(left/max(intervals, 1), 1)
+}
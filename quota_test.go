@@ -0,0 +1,46 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Quota(t *testing.T) {
+	const quotaSize = 1024
+	const window = 500 * time.Millisecond
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, 0, WithQuota(quotaSize, window))
+
+	read(t, ratelimitedReader, quotaSize*2, quotaSize)
+	if _, err := ratelimitedReader.Read(make([]byte, 1)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrQuotaExceeded)
+	}
+
+	time.Sleep(window)
+	read(t, ratelimitedReader, quotaSize*2, quotaSize)
+}
+
+func TestRateLimitedReader_QuotaPartialRead(t *testing.T) {
+	const quotaSize = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, quotaSize*20, WithQuota(quotaSize, time.Hour))
+
+	n, err := ratelimitedReader.Read(make([]byte, quotaSize*2))
+	if n != quotaSize || err != nil {
+		t.Fatalf("expected the quota's worth with no error, got %d bytes with error: %v", n, err)
+	}
+}
+
+func TestRateLimitedReader_SmoothQuota(t *testing.T) {
+	const dataSize = 10 * 1024  // 10KB
+	const bufferSize = dataSize // one read call
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, 0, WithSmoothQuota(dataSize, time.Second))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
@@ -0,0 +1,189 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import "sync"
+
+// readAheadChunkSize bounds each background read when there's no limit to
+// split it by interval.
+const readAheadChunkSize = 32 * 1024
+
+// maxAutoReadAheadSize caps auto sized buffers, so huge limits don't
+// allocate huge buffers, or overflow int on 32-bit platforms.
+const maxAutoReadAheadSize = 64 << 20
+
+// readAhead is a ring buffer filled from the underlying reader at the limited
+// rate in the background.
+type readAhead struct {
+	start sync.Once
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	head   int
+	length int
+	err    error
+	closed bool
+
+	// auto sizes buf to follow the limit
+	auto bool
+}
+
+// WithReadAhead fills a buffer of size bytes from the underlying reader at
+// the limited rate in a background goroutine, started on the first Read, so
+// Read returns quickly from it. Useful when the consumer has bursty work
+// between reads. Close stops the goroutine.
+func WithReadAhead(size int) Option {
+	return func(r *RateLimitedReader) {
+		if size <= 0 {
+			return
+		}
+
+		ra := &readAhead{buf: make([]byte, size)}
+		ra.cond = sync.NewCond(&ra.mu)
+		r.readAhead = ra
+	}
+}
+
+// WithAutoReadAhead is WithReadAhead with the buffer sized to two intervals'
+// worth of the current limit: one being consumed while the next is filled.
+// The buffer follows limit updates, so memory stays proportional to one
+// pacing window.
+func WithAutoReadAhead() Option {
+	return func(r *RateLimitedReader) {
+		ra := &readAhead{auto: true}
+		ra.cond = sync.NewCond(&ra.mu)
+		ra.buf = make([]byte, ra.autoSize(r))
+		r.readAhead = ra
+	}
+}
+
+// read copies buffered data into p, waiting for some to arrive if block is
+// set and returning ErrWouldBlock otherwise.
+func (ra *readAhead) read(r *RateLimitedReader, p []byte, block bool) (int, error) {
+	ra.start.Do(func() { go ra.fill(r) })
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	for ra.length == 0 && ra.err == nil && !ra.closed {
+		if !block {
+			return 0, ErrWouldBlock
+		}
+		ra.cond.Wait()
+	}
+
+	if ra.closed {
+		return 0, ErrClosed
+	}
+	if ra.length == 0 {
+		return 0, ra.err
+	}
+
+	n := 0
+	for n < len(p) && ra.length > 0 {
+		end := min(ra.head+ra.length, len(ra.buf))
+		copied := copy(p[n:], ra.buf[ra.head:end])
+		n += copied
+		ra.head = (ra.head + copied) % len(ra.buf)
+		ra.length -= copied
+	}
+
+	ra.cond.Broadcast()
+	return n, nil
+}
+
+// fill reads from r into the buffer until the underlying reader fails or the
+// reader is closed.
+func (ra *readAhead) fill(r *RateLimitedReader) {
+	chunk := make([]byte, readAheadChunkSize)
+	for {
+		ra.mu.Lock()
+		if ra.auto {
+			ra.resize(ra.autoSize(r))
+		}
+		for ra.length == len(ra.buf) && !ra.closed {
+			ra.cond.Wait()
+		}
+		free := len(ra.buf) - ra.length
+		closed := ra.closed
+		ra.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		chunkSize := min(free, len(chunk))
+		if limit := r.limiter.iterLimit(); limit > 0 && limit < int64(chunkSize) {
+			chunkSize = int(limit)
+		}
+
+		n, err := r.read(chunk[:chunkSize])
+
+		ra.mu.Lock()
+		for written := 0; written < n; {
+			tail := (ra.head + ra.length) % len(ra.buf)
+			end := len(ra.buf)
+			if tail < ra.head {
+				end = ra.head
+			}
+			copied := copy(ra.buf[tail:end], chunk[written:n])
+			written += copied
+			ra.length += copied
+		}
+		if err != nil {
+			ra.err = err
+		}
+		ra.cond.Broadcast()
+		ra.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// autoSize returns the buffer size following r's limit.
+func (ra *readAhead) autoSize(r *RateLimitedReader) int {
+	if limit := r.limiter.iterLimit(); limit > 0 {
+		return int(min(limit*2, maxAutoReadAheadSize))
+	}
+	return readAheadChunkSize * 2
+}
+
+// resize moves the buffered data to a buffer of size bytes, unless it
+// wouldn't fit yet. Must hold ra.mu.
+func (ra *readAhead) resize(size int) {
+	if size == len(ra.buf) || size < ra.length {
+		return
+	}
+
+	buf := make([]byte, size)
+	for copied := 0; copied < ra.length; {
+		end := min(ra.head+ra.length-copied, len(ra.buf))
+		n := copy(buf[copied:], ra.buf[ra.head:end])
+		copied += n
+		ra.head = (ra.head + n) % len(ra.buf)
+	}
+	ra.buf = buf
+	ra.head = 0
+}
+
+// occupancy returns how many bytes are buffered out of the buffer's size.
+func (ra *readAhead) occupancy() (buffered, size int) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	return ra.length, len(ra.buf)
+}
+
+func (ra *readAhead) close() {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	ra.closed = true
+	ra.cond.Broadcast()
+}
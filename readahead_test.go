@@ -0,0 +1,172 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_ReadAhead(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithReadAhead(dataSize))
+	defer ratelimitedReader.Close()
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedReader_ReadAheadReturnsQuickly(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const limit = dataSize // a second for all the data
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithReadAhead(dataSize))
+	defer ratelimitedReader.Close()
+
+	// start filling, then let the consumer be busy while the buffer fills
+	read(t, ratelimitedReader, bufferSize, bufferSize)
+	time.Sleep(1500 * time.Millisecond)
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize-bufferSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestRateLimitedReader_ReadAheadDataHermetics(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB
+	const bufferSize = 700     // not aligned with the ring buffer
+	const limit = dataSize * 10
+
+	data := strings.Repeat("0123456789", dataSize/10)
+	reader := strings.NewReader(data)
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithReadAhead(1000))
+	defer ratelimitedReader.Close()
+
+	got, err := read(t, ratelimitedReader, bufferSize, dataSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != data {
+		t.Fatalf("read incorrect data through the ring buffer")
+	}
+}
+
+func TestRateLimitedReader_ReadAheadClose(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithReadAhead(limit))
+	if _, err := ratelimitedReader.Read(make([]byte, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ratelimitedReader.Close(); err != nil {
+		t.Fatalf("unexpected error while closing: %v", err)
+	}
+	if _, err := ratelimitedReader.Read(make([]byte, 10)); err != ErrClosed {
+		t.Fatalf("unexpected error after close: %v expected: %v", err, ErrClosed)
+	}
+}
+
+func TestRateLimitedReader_ReadAheadTryRead(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithReadAhead(limit))
+	defer ratelimitedReader.Close()
+
+	if _, err := ratelimitedReader.TryRead(make([]byte, 10)); err != ErrWouldBlock {
+		t.Fatalf("expected try read on an empty buffer to block, err: %v", err)
+	}
+}
+
+func TestRateLimitedReader_AutoReadAhead(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithAutoReadAhead())
+	defer ratelimitedReader.Close()
+
+	iterLimit := int(limit / (1000 / ReadIntervalMilliseconds))
+	if size := ratelimitedReader.Stats().BufferSize; size != iterLimit*2 {
+		t.Fatalf("unexpected buffer size: %d expected: %d", size, iterLimit*2)
+	}
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedReader_AutoReadAheadFollowsLimit(t *testing.T) {
+	const limit = 20 * 1024
+	const bufferSize = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithAutoReadAhead())
+	defer ratelimitedReader.Close()
+
+	read(t, ratelimitedReader, bufferSize, bufferSize)
+	ratelimitedReader.UpdateLimit(limit * 2)
+
+	// the buffer is resized when the filler comes around to it
+	iterLimit := int(limit * 2 / (1000 / ReadIntervalMilliseconds))
+	deadline := time.Now().Add(time.Second)
+	for ratelimitedReader.Stats().BufferSize != iterLimit*2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("buffer not resized, size: %d expected: %d", ratelimitedReader.Stats().BufferSize, iterLimit*2)
+		}
+		read(t, ratelimitedReader, bufferSize, bufferSize)
+	}
+
+	stats := ratelimitedReader.Stats()
+	if stats.BufferedBytes > stats.BufferSize {
+		t.Fatalf("buffered more than the buffer holds, buffered: %d size: %d", stats.BufferedBytes, stats.BufferSize)
+	}
+}
+
+func TestRateLimitedReader_StatsBufferOccupancy(t *testing.T) {
+	const limit = 20 * 1024
+	const readAheadSize = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithReadAhead(readAheadSize))
+	defer ratelimitedReader.Close()
+
+	ratelimitedReader.Read(make([]byte, 1))
+	time.Sleep(200 * time.Millisecond) // long enough to fill the buffer
+
+	stats := ratelimitedReader.Stats()
+	if stats.BufferSize != readAheadSize || stats.BufferedBytes != readAheadSize {
+		t.Fatalf("unexpected buffer stats, buffered: %d size: %d expected: %d", stats.BufferedBytes, stats.BufferSize, readAheadSize)
+	}
+}
+
+func TestRateLimitedReader_StatsSourceAndDeliveredBytes(t *testing.T) {
+	const limit = 20 * 1024
+	const readAheadSize = 1024
+	const bufferSize = readAheadSize / 4
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit, WithReadAhead(readAheadSize))
+	defer ratelimitedReader.Close()
+
+	ratelimitedReader.Read(make([]byte, bufferSize))
+	time.Sleep(200 * time.Millisecond) // long enough to fill the buffer
+
+	stats := ratelimitedReader.Stats()
+	if stats.DeliveredBytes != bufferSize {
+		t.Fatalf("unexpected delivered bytes: %d expected: %d", stats.DeliveredBytes, bufferSize)
+	}
+	// read-ahead pulled the buffer's worth past what was delivered
+	if stats.SourceBytes != bufferSize+readAheadSize {
+		t.Fatalf("unexpected source bytes: %d expected: %d", stats.SourceBytes, bufferSize+readAheadSize)
+	}
+}
@@ -1,114 +1,1033 @@
-package v6
+// Package ratelimitedreader paces readers, writers and conns to a limit in
+// bytes per second, granting each interval's budget in turn so data streams
+// smoothly rather than in bursts. The pacing of earlier major versions is
+// kept as selectable algorithms, see RegisterAlgorithm.
+package ratelimitedreader
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"sync/atomic"
 	"time"
 )
 
 var (
+	// ReadIntervalMilliseconds is the interval limiters pace in unless given
+	// one of their own.
+	//
+	// Deprecated: set the interval per reader with WithInterval or
+	// SetInterval, or per limiter with Limiter.SetInterval; changing it
+	// changes every limiter without one.
 	ReadIntervalMilliseconds int64 = 50
 )
 
+// defaultSleepSlice is how long a reader sleeps at once by default, see
+// WithSleepSlice.
+const defaultSleepSlice = 100 * time.Millisecond
+
 type RateLimitedReader struct {
-	reader          io.ReadCloser
-	limit           atomic.Int64
-	iterTotalRead   atomic.Int64
-	lastElapsed     atomic.Int64
-	timeSlept       atomic.Int64
-	timeAccumulated atomic.Int64
+	reader        io.ReadCloser
+	limiter       *Limiter
+	ownLimiter    Limiter
+	iterTotalRead atomic.Int64
+	waiting       atomic.Bool
+	throttledC    chan struct{}
+	pollMode      atomic.Bool
+	readAhead     *readAhead
+
+	// algorithm paces reads in place of limiter, see WithAlgorithm
+	algorithm Algorithm
+
+	// priority orders r's grants on a shared limiter, see WithPriority
+	priority int
+
+	// fair serves concurrent callers in turns, see WithFairReads
+	fair *fairQueue
+
+	// optErr is a configuration error from the options, see NewReaderE
+	optErr error
+
+	// opts are the options r was given, see NewFromSame
+	opts []Option
+
+	// eof is set once the underlying reader returned io.EOF, pendingEOF when
+	// that EOF is still to be reported
+	eof        atomic.Bool
+	pendingEOF atomic.Bool
+	deferEOF   bool
+
+	// pendingErr is an underlying error held back for the next read, see
+	// WithPartialOnTimeout
+	pendingErr       error
+	partialOnTimeout bool
+
+	// totalRead and startedAt, in unix nanoseconds, describe the transfer
+	// for TransferError
+	totalRead atomic.Int64
+	startedAt atomic.Int64
+
+	// wakeups counts the sleeps taken, see MeasureOverhead
+	wakeups atomic.Int64
+
+	// tiers are the limits the stream steps through, nextTier the index of
+	// the first not yet applied and tierLimit the limit of the last applied,
+	// if tiered, see WithTiers
+	tiers     []Tier
+	nextTier  int
+	tierLimit int64
+	tiered    bool
+
+	// boostLimit applies to the first boostBytes of the stream while
+	// boosting, see WithBoost
+	boostBytes int64
+	boostLimit int64
+	boosting   bool
+
+	// bufferSizes counts the sizes consumers read with, readSizes those the
+	// underlying reader completed, see WithAutoChunk, and returnedSizes
+	// those handed back to consumers
+	bufferSizes   sizeHistogram
+	readSizes     sizeHistogram
+	returnedSizes sizeHistogram
+	autoChunk     bool
+
+	// alignment is what grants are rounded to, see WithAlignment, and
+	// onGrant is told every grant, see WithOnGrant
+	alignment int64
+	onGrant   func(size int64)
+
+	// sourceRate is how fast the underlying reader delivers, in bytes per
+	// second, see WithLatencyAwareChunks
+	latencyAware bool
+	sourceRate   atomic.Int64
+
+	quota *quota
+
+	// grace lets reads exceed the limit for a while, see WithGraceBurst
+	grace *grace
+	// debt is what reads borrowed from future intervals, see WithBorrowing
+	debt *debt
+	// timeScale scales pacing delays, 0 leaving them be, see WithTimeScale
+	timeScale float64
+
+	pacing Pacing
+
+	// stopC aborts sleeps once closed, see WithStopChannel
+	stopC <-chan struct{}
+
+	// hold holds reads while paused, see Pause
+	hold hold
+
+	// closed is set by Close, sleepSlice bounds how long r sleeps at once
+	// before checking it, see WithSleepSlice
+	closed     atomic.Bool
+	sleepSlice time.Duration
+
+	// highResTimer is set while r holds a high timer resolution, see
+	// WithHighResolutionTimer
+	highResTimer atomic.Bool
+
+	// idleTimer closes the reader after idleTimeout without reads, see
+	// WithIdleClose
+	idleTimer   *time.Timer
+	idleTimeout time.Duration
+	idleClosed  atomic.Bool
+
+	// keepalive is called every keepalivePeriod a read spends sleeping, next
+	// at nextKeepalive, see WithKeepalive
+	keepalive       func(ctx context.Context)
+	keepalivePeriod time.Duration
+	nextKeepalive   time.Time
+
+	// onThrottle is called for every sleep for the limit, see WithOnThrottle
+	onThrottle func(ctx context.Context, wait time.Duration)
+
+	// readCtx is the context of the ReadContext in progress, if any
+	readCtx atomic.Pointer[context.Context]
+
+	// hash is fed every byte delivered, see WithHash
+	hash hash.Hash
+
+	// usage reports the bytes delivered, see WithUsageReporter
+	usage *usageTap
+
+	// sourceBytes counts the bytes read from the underlying reader and
+	// deliveredBytes those handed to callers
+	sourceBytes    atomic.Int64
+	deliveredBytes atomic.Int64
+
+	// throughput measures deliveries, see CurrentThroughput
+	throughput   throughputMeter
+	notBinding   *notBinding
+	minRateAlert *minRateAlert
+
+	// throttledFor sums the time spent sleeping for the limiter
+	throttledFor atomic.Int64
+
+	// lastByte is the byte ReadByte last returned, if hasLastByte, and
+	// byteUnread is set once UnreadByte gave it back
+	lastByte    byte
+	hasLastByte bool
+	byteUnread  bool
+
+	// onEOF and onClose are called once the transfer ends, see WithOnEOF
+	// and WithOnClose
+	onEOF   *lifecycleCallback
+	onClose *lifecycleCallback
+
+	// retry retries transient errors from the underlying reader, see
+	// WithRetry
+	retry *RetryPolicy
 }
 
-func NewRateLimitedReader(reader io.Reader, limit int64) *RateLimitedReader {
-	return NewRateLimitedReadCloser(io.NopCloser(reader), limit)
+// Option configures a RateLimitedReader at construction.
+type Option func(r *RateLimitedReader)
+
+func NewRateLimitedReader(reader io.Reader, limit int64, opts ...Option) *RateLimitedReader {
+	return NewRateLimitedReadCloser(io.NopCloser(reader), limit, opts...)
 }
 
-func NewRateLimitedReadCloser(reader io.ReadCloser, limit int64) *RateLimitedReader {
-	r := &RateLimitedReader{
-		reader: reader,
+func NewRateLimitedReadCloser(reader io.ReadCloser, limit int64, opts ...Option) *RateLimitedReader {
+	r := &RateLimitedReader{}
+	r.init(reader, nil, limit, opts...)
+	return r
+}
+
+// NewReaderE is NewRateLimitedReader validating its configuration up front:
+// it returns ErrNilReader for a nil reader and ErrInvalidLimit for a negative
+// limit, a positive limit too small to be paced in the reader's interval
+// steps, or an interval outside (0, 1s], and ErrUnknownAlgorithm for a
+// WithAlgorithmName name never registered.
+func NewReaderE(reader io.Reader, limit int64, opts ...Option) (*RateLimitedReader, error) {
+	if reader == nil {
+		return nil, ErrNilReader
 	}
 
-	r.limit.Store(limit)
-	r.iterTotalRead.Store(0)
-	r.lastElapsed.Store(0)
-	r.timeSlept.Store(0)
-	r.timeAccumulated.Store(0)
+	return NewReadCloserE(io.NopCloser(reader), limit, opts...)
+}
+
+// NewReadCloserE is NewRateLimitedReadCloser validating its configuration up
+// front, see NewReaderE.
+func NewReadCloserE(reader io.ReadCloser, limit int64, opts ...Option) (*RateLimitedReader, error) {
+	if reader == nil {
+		return nil, ErrNilReader
+	}
+	if err := validateLimit(limit, time.Duration(ReadIntervalMilliseconds)*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	r := NewRateLimitedReadCloser(reader, limit, opts...)
+	err := r.optErr
+	if err == nil {
+		err = validateLimit(limit, r.limiter.Interval())
+	}
+	if err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+// validateLimit reports whether limit can be paced in interval steps.
+func validateLimit(limit int64, interval time.Duration) error {
+	if err := validateInterval(interval); err != nil {
+		return err
+	}
+	if limit < 0 {
+		return fmt.Errorf("%w: negative limit %d", ErrInvalidLimit, limit)
+	}
+	if limit > 0 && mulDiv(limit, int64(interval), int64(time.Second)) < 1 {
+		return fmt.Errorf("%w: limit %d is below one byte per %v interval", ErrInvalidLimit, limit, interval)
+	}
+	return nil
+}
+
+func validateInterval(interval time.Duration) error {
+	if interval <= 0 || interval > time.Second {
+		return fmt.Errorf("%w: read interval of %v", ErrInvalidLimit, interval)
+	}
+	return nil
+}
+
+// WithInterval paces the reader in interval steps rather than
+// ReadIntervalMilliseconds, on its limiter, so on a shared limiter for all
+// its readers. An interval outside (0, 1s] is ignored, and makes NewReaderE
+// fail with ErrInvalidLimit.
+func WithInterval(interval time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		if err := r.limiter.SetInterval(interval); err != nil {
+			r.optErr = err
+		}
+	}
+}
+
+// WithPassthrough puts the reader's limiter in passthrough mode, see
+// Limiter.SetPassthrough, so on a shared limiter all its readers pass
+// through.
+func WithPassthrough() Option {
+	return func(r *RateLimitedReader) {
+		r.limiter.SetPassthrough(true)
+	}
+}
+
+// SetInterval changes the interval the reader paces in, see WithInterval.
+func (r *RateLimitedReader) SetInterval(interval time.Duration) error {
+	return r.limiter.SetInterval(interval)
+}
+
+// NewReaderWithLimiter returns a reader drawing from limiter, so readers
+// built against one limiter together keep to its limit, which SetLimit
+// changes for all of them. A nil limiter leaves the reader unlimited.
+func NewReaderWithLimiter(reader io.Reader, limiter *Limiter, opts ...Option) *RateLimitedReader {
+	return NewReadCloserWithLimiter(io.NopCloser(reader), limiter, opts...)
+}
+
+func NewReadCloserWithLimiter(reader io.ReadCloser, limiter *Limiter, opts ...Option) *RateLimitedReader {
+	return newRateLimitedReadCloser(reader, limiter, opts...)
+}
+
+// NewFromSame returns a reader over reader configured like existing: it
+// draws from the same limiter and is given the same options, so a dropped
+// stream can be reconnected mid-transfer and keep its pacing. Shared state
+// such as a WithHash hash carries over; per stream state such as tier
+// progress, quota usage and Stats starts afresh, unless carried over with
+// AdoptState. reader is closed with the returned reader if it's an
+// io.ReadCloser.
+func NewFromSame(existing *RateLimitedReader, reader io.Reader) *RateLimitedReader {
+	readCloser, ok := reader.(io.ReadCloser)
+	if !ok {
+		readCloser = io.NopCloser(reader)
+	}
+	return newRateLimitedReadCloser(readCloser, existing.limiter, existing.opts...)
+}
+
+// AdoptState carries old's budget over to r, for when a dropped stream is
+// wrapped anew: what old's limiter has booked ahead, its quota usage, its
+// borrowing debt and its grace allowance used, so a reconnect loop can't
+// reset the budget. What r isn't configured for is left behind. Call it
+// before reading from r and once done reading from old.
+func (r *RateLimitedReader) AdoptState(old *RateLimitedReader) {
+	if r.limiter != old.limiter {
+		r.limiter.adopt(old.limiter)
+	}
+	if r.quota != nil && old.quota != nil {
+		r.quota.start, r.quota.used = old.quota.start, old.quota.used
+	}
+	if r.debt != nil && old.debt != nil {
+		r.debt.owed = min(old.debt.owed, r.debt.max)
+	}
+	if r.grace != nil && old.grace != nil {
+		r.grace.start, r.grace.used = old.grace.start, old.grace.used
+	}
+}
+
+func newRateLimitedReadCloser(reader io.ReadCloser, limiter *Limiter, opts ...Option) *RateLimitedReader {
+	r := &RateLimitedReader{}
+	r.init(reader, limiter, 0, opts...)
 	return r
 }
 
-func (r *RateLimitedReader) Read(p []byte) (n int, err error) {
+// init (re)sets r to read from reader, drawing from limiter or, when nil, from
+// a limiter of its own set to limit.
+func (r *RateLimitedReader) init(reader io.ReadCloser, limiter *Limiter, limit int64, opts ...Option) {
+	throttledC := r.throttledC
+	if throttledC == nil {
+		throttledC = make(chan struct{}, 1)
+	}
+	select {
+	case <-throttledC:
+	default:
+	}
+
+	*r = RateLimitedReader{
+		reader:     reader,
+		limiter:    limiter,
+		throttledC: throttledC,
+		sleepSlice: defaultSleepSlice,
+		opts:       opts,
+	}
+	if limiter == nil {
+		r.ownLimiter.limit.Store(limit)
+		r.limiter = &r.ownLimiter
+	}
+
 	r.iterTotalRead.Store(0)
+	for _, opt := range opts {
+		opt(r)
+	}
+}
+
+func (r *RateLimitedReader) Read(p []byte) (n int, err error) {
+	return r.ReadContext(context.Background(), p)
+}
+
+// ReadContext is Read on behalf of ctx: sleeping for the limit ends with
+// ctx's error once ctx is done, and hooks called from the read, such as
+// those of WithOnEOFContext, WithKeepaliveContext and WithOnThrottle, get
+// ctx, so trace IDs and tenant info flow into the telemetry they emit. With
+// read-ahead, ctx reaches hooks but doesn't end the wait for buffered data.
+func (r *RateLimitedReader) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	if r.fair != nil {
+		r.fair.acquire()
+		defer r.fair.release()
+		p = r.fairShare(p)
+	}
+
+	r.readCtx.Store(&ctx)
+	defer func() {
+		r.readCtx.Store(nil)
+		if errors.Is(err, ErrClosed) && ctx.Err() != nil && !r.closed.Load() {
+			err = ctx.Err()
+		}
+	}()
+
+	if r.idleTimer != nil {
+		r.idleTimer.Stop()
+		defer r.idleTimer.Reset(r.idleTimeout)
+	}
+
+	if n, ok := r.readUnreadByte(p); ok {
+		return n, nil
+	}
+	defer func() {
+		r.deliver(p[:n])
+		r.ended(err)
+	}()
+
+	if r.pollMode.Load() {
+		n, err = r.tryRead(p)
+		if err == ErrWouldBlock {
+			return 0, nil
+		}
+		return n, err
+	}
+
+	r.bufferSizes.add(len(p))
+	if err := r.waitResumed(); err != nil {
+		return 0, err
+	}
+
+	if r.readAhead != nil {
+		return r.readAhead.read(r, p, true)
+	}
+
+	return r.read(p)
+}
+
+// WithDeferredEOF makes a Read that hits the underlying EOF after reading
+// some data return that data with a nil error, and io.EOF on the next call.
+// By default io.EOF is returned along with the data.
+func WithDeferredEOF() Option {
+	return func(r *RateLimitedReader) {
+		r.deferEOF = true
+	}
+}
+
+// WithPartialOnTimeout makes a Read whose underlying read times out, see
+// IsTimeout, after some data was gathered return that data right away with a
+// nil error, and the error, as a TransferError, on the next call, so
+// callers checking the error first don't lose track of what was delivered.
+// With PaceAfterRead the data isn't held back for its sleep either, which
+// the next read makes up for. By default the data is returned along with
+// the error.
+func WithPartialOnTimeout() Option {
+	return func(r *RateLimitedReader) {
+		r.partialOnTimeout = true
+	}
+}
+
+// surfacesPartial reports whether err from the underlying reader is held
+// back for the next read, see WithPartialOnTimeout.
+func (r *RateLimitedReader) surfacesPartial(err error) bool {
+	return r.partialOnTimeout && err != nil && IsTimeout(err)
+}
+
+// read fills p from the underlying reader at the limited rate.
+func (r *RateLimitedReader) read(p []byte) (n int, err error) {
+	r.begin()
+	if r.stopped() {
+		return 0, ErrClosed
+	}
+	if r.pendingEOF.Swap(false) {
+		return 0, io.EOF
+	}
+	if err := r.pendingErr; err != nil {
+		r.pendingErr = nil
+		return 0, err
+	}
+	if r.eof.Load() {
+		return r.readAfterEOF(p)
+	}
+
 	chunkSize := int64(len(p))
 	for r.iterTotalRead.Load() < chunkSize {
-		limit := r.limit.Load()
-		if limit <= 0 {
-			n, err = r.readWithoutLimit(p[r.iterTotalRead.Load():int(chunkSize)])
+		allowedBytes := chunkSize - r.iterTotalRead.Load()
+		if tierLeft := r.applyTiers(); tierLeft > 0 && tierLeft < allowedBytes {
+			allowedBytes = tierLeft
+		}
+		if r.quota != nil {
+			quotaLeft := r.quota.left(time.Now())
+			if quotaLeft <= 0 {
+				if r.iterTotalRead.Load() == 0 {
+					return 0, ErrQuotaExceeded
+				}
+				break
+			}
+			allowedBytes = min(allowedBytes, quotaLeft)
+		}
+
+		if r.algorithm != nil {
+			grant, wait := r.algorithm.Grant(allowedBytes, time.Now())
+			if sleepErr := r.wait(wait, 0, 0); sleepErr != nil {
+				return r.abort(sleepErr)
+			}
+
+			grant = min(grant, allowedBytes)
+			if grant <= 0 {
+				continue
+			}
+			r.granted(grant)
+			n, err = r.readUnderlying(p[r.iterTotalRead.Load():int(r.iterTotalRead.Load()+grant)])
 			r.iterTotalRead.Add(int64(n))
-			return int(r.iterTotalRead.Load()), err
+			if err != nil {
+				break
+			}
+			continue
 		}
 
-		// the limit set to per second
-		limit = limit / (1000 / ReadIntervalMilliseconds)
+		limit := r.paceLimit()
+		if limit <= 0 {
+			n, err = r.readWithoutLimit(p[r.iterTotalRead.Load():int(r.iterTotalRead.Load()+allowedBytes)])
+			r.iterTotalRead.Add(int64(n))
+			return r.endRead(err)
+		}
 
-		allowedBytes := limit
-		chunkSizeLeft := chunkSize - r.iterTotalRead.Load()
-		if chunkSizeLeft < allowedBytes {
-			allowedBytes = chunkSizeLeft
+		var borrowed int64
+		if grantSize := r.grantSize(limit); grantSize < allowedBytes {
+			if r.debt != nil {
+				borrowed = r.debt.borrow(allowedBytes - grantSize)
+			}
+			allowedBytes = grantSize
 		}
 
-		r.sleep(allowedBytes, limit)
+		r.granted(allowedBytes + borrowed)
+		if r.pacing == PaceAfterRead {
+			n, err = r.readUnderlying(p[r.iterTotalRead.Load():int(r.iterTotalRead.Load()+allowedBytes+borrowed)])
+			r.iterTotalRead.Add(int64(n))
+			if paid := int64(n) - borrowed; paid > 0 && r.surfacesPartial(err) {
+				// booked for the next read to wait for, so the data goes out now
+				r.limiter.take(paid, limit)
+			} else if paid > 0 {
+				if sleepErr := r.sleep(paid, limit); sleepErr != nil {
+					return r.abort(sleepErr)
+				}
+			}
+		} else {
+			if sleepErr := r.sleep(allowedBytes, limit); sleepErr != nil {
+				r.unborrow(borrowed)
+				return r.abort(sleepErr)
+			}
 
-		n, err = r.reader.Read(p[r.iterTotalRead.Load():int(r.iterTotalRead.Load()+allowedBytes)])
-		r.iterTotalRead.Add(int64(n))
+			n, err = r.readUnderlying(p[r.iterTotalRead.Load():int(r.iterTotalRead.Load()+allowedBytes+borrowed)])
+			r.iterTotalRead.Add(int64(n))
+			if r.pacing == PaceProportional && int64(n) < allowedBytes {
+				r.limiter.refund(allowedBytes-int64(n), limit)
+			}
+		}
+		// what wasn't read of the loan isn't owed
+		r.unborrow(min(borrowed, allowedBytes+borrowed-int64(n)))
 		if err != nil {
 			break
 		}
 	}
 
-	return int(r.iterTotalRead.Load()), err
+	return r.endRead(err)
+}
+
+// readAfterEOF reads from an underlying reader that already returned io.EOF
+// without sleeping first, as it most likely will again. Should the source
+// have grown since, the bytes are paced after the fact.
+func (r *RateLimitedReader) readAfterEOF(p []byte) (n int, err error) {
+	limit := r.limiter.iterLimit()
+	if limit > 0 && int64(len(p)) > limit {
+		p = p[:limit]
+	}
+
+	n, err = r.readUnderlying(p)
+	r.iterTotalRead.Store(int64(n))
+	if n > 0 {
+		r.eof.Store(false)
+		if limit > 0 {
+			if sleepErr := r.sleep(int64(n), limit); sleepErr != nil {
+				return r.abort(sleepErr)
+			}
+		}
+	}
+	return r.endRead(err)
+}
+
+// abort ends the current call with err, which doesn't come from the
+// underlying reader.
+func (r *RateLimitedReader) abort(err error) (int, error) {
+	n, _ := r.endRead(nil)
+	return n, err
+}
+
+// begin starts a read call, and the transfer on the first one.
+func (r *RateLimitedReader) begin() {
+	r.iterTotalRead.Store(0)
+	r.startedAt.CompareAndSwap(0, time.Now().UnixNano())
+	if r.keepalive != nil {
+		r.nextKeepalive = time.Now().Add(r.keepalivePeriod)
+	}
+}
+
+// endRead returns what the current call read along with err, keeping track
+// of the underlying reader reaching EOF and wrapping its other errors in a
+// TransferError.
+func (r *RateLimitedReader) endRead(err error) (int, error) {
+	n := int(r.iterTotalRead.Load())
+	r.totalRead.Add(int64(n))
+	if err != nil && err != io.EOF {
+		transferErr := &TransferError{
+			Err:       err,
+			BytesRead: r.totalRead.Load(),
+			Elapsed:   time.Since(time.Unix(0, r.startedAt.Load())),
+			Limit:     r.limiter.Limit(),
+		}
+		if n > 0 && r.surfacesPartial(err) {
+			r.pendingErr = transferErr
+			return n, nil
+		}
+		return n, transferErr
+	}
+
+	if err == io.EOF {
+		r.eof.Store(true)
+		if r.deferEOF && n > 0 {
+			r.pendingEOF.Store(true)
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// TryRead reads as much of p as the limit allows right now without sleeping,
+// for event-loop style consumers. It returns ErrWouldBlock when no budget is
+// available, in which case the caller should retry later.
+func (r *RateLimitedReader) TryRead(p []byte) (n int, err error) {
+	if r.fair != nil {
+		if !r.fair.tryAcquire() {
+			return 0, ErrWouldBlock
+		}
+		defer r.fair.release()
+	}
+
+	if r.idleTimer != nil {
+		r.idleTimer.Stop()
+		defer r.idleTimer.Reset(r.idleTimeout)
+	}
+
+	if n, ok := r.readUnreadByte(p); ok {
+		return n, nil
+	}
+	defer func() {
+		r.deliver(p[:n])
+		r.ended(err)
+	}()
+
+	return r.tryRead(p)
+}
+
+func (r *RateLimitedReader) tryRead(p []byte) (n int, err error) {
+	r.bufferSizes.add(len(p))
+	if r.Paused() {
+		return 0, ErrPaused
+	}
+	if r.readAhead != nil {
+		return r.readAhead.read(r, p, false)
+	}
+
+	r.begin()
+	if r.stopped() {
+		return 0, ErrClosed
+	}
+	if r.pendingEOF.Swap(false) {
+		return 0, io.EOF
+	}
+	if err := r.pendingErr; err != nil {
+		r.pendingErr = nil
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if tierLeft := r.applyTiers(); tierLeft > 0 && tierLeft < int64(len(p)) {
+		p = p[:tierLeft]
+	}
+	if r.quota != nil {
+		quotaLeft := r.quota.left(time.Now())
+		if quotaLeft <= 0 {
+			return 0, ErrQuotaExceeded
+		}
+		p = p[:min(int64(len(p)), quotaLeft)]
+	}
+
+	limit := r.paceLimit()
+	if limit <= 0 {
+		n, err = r.readWithoutLimit(p)
+		r.iterTotalRead.Store(int64(n))
+		return r.endRead(err)
+	}
+
+	allowedBytes := r.limiter.tryTake(int64(len(p)), limit)
+	if allowedBytes <= 0 {
+		return 0, ErrWouldBlock
+	}
+	r.granted(allowedBytes)
+
+	n, err = r.readUnderlying(p[:allowedBytes])
+	r.iterTotalRead.Store(int64(n))
+	return r.endRead(err)
 }
 
 func (r *RateLimitedReader) readWithoutLimit(p []byte) (n int, err error) {
-	return r.reader.Read(p)
+	return r.readUnderlying(p)
 }
 
-func (r *RateLimitedReader) sleep(allowedBytes, iterLimit int64) {
-	expectedTime := allowedBytes * ReadIntervalMilliseconds * int64(time.Millisecond) / iterLimit
+// readUnderlying reads from the underlying reader, accounting the read for
+// stats, grant sizing and quota.
+func (r *RateLimitedReader) readUnderlying(p []byte) (n int, err error) {
+	start := time.Now()
+	n, err = r.readSource(p)
+	r.sourceBytes.Add(int64(n))
+	r.readSizes.add(n)
+	if r.latencyAware {
+		r.observeLatency(n, time.Since(start))
+	}
+	if r.quota != nil {
+		r.quota.used += int64(n)
+	}
+	if r.debt != nil {
+		r.debt.use(n)
+	}
+	if r.grace != nil {
+		r.grace.use(n)
+	}
+	return n, err
+}
 
-	now := time.Now().UnixNano()
-	elapsed := now - r.lastElapsed.Load() - r.timeSlept.Load()
-	if elapsed > int64(time.Second) {
-		elapsed = 0
-		r.lastElapsed.Store(now)
-		r.timeSlept.Store(0)
-		r.timeAccumulated.Store(0)
+// deliver accounts for bytes handed to a caller, feeding them to the hash.
+func (r *RateLimitedReader) deliver(delivered []byte) {
+	if len(delivered) == 0 {
+		return
 	}
 
-	sleepTime := r.timeAccumulated.Load() - (elapsed - expectedTime)
-	if sleepTime > 0 {
-		time.Sleep(time.Duration(sleepTime))
-		r.timeAccumulated.Store(0)
-		if elapsed == 0 {
-			r.timeSlept.Add(sleepTime)
-		} else {
-			r.timeSlept.Store(0)
-			r.lastElapsed.Store(now + sleepTime)
+	r.deliveredBytes.Add(int64(len(delivered)))
+	r.returnedSizes.add(len(delivered))
+	now := time.Now()
+	r.throughput.add(now, int64(len(delivered)))
+	if r.notBinding != nil {
+		r.notBinding.observe(now, r.throughput.rate(now), r.limiter.Limit())
+	}
+	if r.hash != nil {
+		r.hash.Write(delivered)
+	}
+	if r.usage != nil {
+		r.usage.add(int64(len(delivered)))
+	}
+}
+
+// paceLimit returns the limit per read interval to pace at, or 0 when
+// unlimited.
+func (r *RateLimitedReader) paceLimit() int64 {
+	limit := r.tieredLimit(r.limiter.iterLimit())
+	if r.grace != nil && limit > 0 {
+		limit = r.grace.iterLimit(time.Now(), limit, r.limiter.Limit())
+	}
+	if r.debt != nil && limit > 0 {
+		limit = r.debt.iterLimit(limit)
+	}
+	if r.quota != nil && r.quota.smooth {
+		if quotaLimit := r.quota.iterLimit(time.Now(), r.limiter.Interval()); limit <= 0 || quotaLimit < limit {
+			limit = quotaLimit
 		}
-	} else {
-		r.timeAccumulated.Store(sleepTime)
-		r.timeSlept.Store(0)
-		r.lastElapsed.Store(now)
 	}
+	return r.scaled(limit)
+}
+
+// sleep waits for allowedBytes' turn, returning ErrClosed if stopped first.
+func (r *RateLimitedReader) sleep(allowedBytes, iterLimit int64) error {
+	if q := r.limiter.priorities.Load(); q != nil {
+		q.acquire(r.priority)
+		defer q.release()
+	}
+
+	return r.wait(r.limiter.take(allowedBytes, iterLimit), allowedBytes, iterLimit)
+}
+
+// wait sleeps for sleepTime on behalf of the limit, in slices of at most
+// sleepSlice to keep up keepalives and react to Close and stopping, returning
+// ErrClosed, and to limit or interval changes, rebooking what's left of
+// allowedBytes at the new ones. allowedBytes 0 leaves the booking as is.
+func (r *RateLimitedReader) wait(sleepTime time.Duration, allowedBytes, iterLimit int64) error {
+	if sleepTime <= 0 {
+		r.waiting.Store(false)
+		return nil
+	}
+
+	r.wakeups.Add(1)
+	if allowedBytes > 0 {
+		defer r.limiter.enqueue(allowedBytes)()
+	}
+	if r.onThrottle != nil {
+		r.onThrottle(r.context(), sleepTime)
+	}
+	if r.waiting.CompareAndSwap(false, true) {
+		select {
+		case r.throttledC <- struct{}{}:
+		default:
+		}
+	}
+
+	limit, interval := r.limiter.Limit(), r.limiter.Interval()
+	wakeAt := time.Now().Add(sleepTime)
+	for {
+		left := time.Until(wakeAt)
+		if left <= 0 {
+			return nil
+		}
+		if r.stopped() {
+			return ErrClosed
+		}
+
+		newLimit, newInterval := r.limiter.Limit(), r.limiter.Interval()
+		if allowedBytes > 0 && (newLimit != limit || newInterval != interval) {
+			// rebook what's left of the grant at the new limit and interval
+			leftBytes := mulDiv(allowedBytes, int64(left), int64(sleepTime))
+			r.limiter.refundTime(left)
+
+			limit, interval = newLimit, newInterval
+			iterLimit = r.paceLimit()
+			if iterLimit <= 0 {
+				return nil
+			}
+			allowedBytes = leftBytes
+			sleepTime = r.limiter.take(allowedBytes, iterLimit)
+			wakeAt = time.Now().Add(sleepTime)
+			continue
+		}
+
+		step := left
+		if r.sleepSlice > 0 {
+			step = min(step, r.sleepSlice)
+		}
+		if r.keepalive != nil {
+			if due := time.Until(r.nextKeepalive); due <= 0 {
+				r.keepalive(r.context())
+				r.nextKeepalive = time.Now().Add(r.keepalivePeriod)
+				continue
+			} else if due < step {
+				step = due
+			}
+		}
+
+		if err := r.pause(step); err != nil {
+			return err
+		}
+	}
+}
+
+// WithSleepSlice bounds how long the reader sleeps at once to slice, which
+// bounds how late it notices Close, the stop channel or a limit change in a
+// long sleep. It defaults to defaultSleepSlice; 0 or below sleeps in one go,
+// noticing only the stop channel.
+func WithSleepSlice(slice time.Duration) Option {
+	return func(r *RateLimitedReader) {
+		r.sleepSlice = slice
+	}
+}
+
+// pause sleeps for d, returning ErrClosed if stopped first.
+func (r *RateLimitedReader) pause(d time.Duration) error {
+	start := time.Now()
+	defer func() { r.throttledFor.Add(int64(time.Since(start))) }()
+
+	return r.sleepUnlessStopped(d)
+}
+
+// sleepUnlessStopped sleeps for d, returning ErrClosed if the stop channel
+// closes first.
+func (r *RateLimitedReader) sleepUnlessStopped(d time.Duration) error {
+	done := r.readDone()
+	if r.stopC == nil && done == nil {
+		time.Sleep(d)
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.stopC:
+		return ErrClosed
+	case <-done:
+		return ErrClosed
+	}
+}
+
+// seeked forgets state tied to the underlying reader's position, after it was
+// moved by a seek.
+func (r *RateLimitedReader) seeked() {
+	r.eof.Store(false)
+	r.pendingEOF.Store(false)
+	r.hasLastByte = false
+	r.byteUnread = false
+}
+
+// WithKeepalive calls fn every period that a single read spends throttled,
+// so callers can extend external deadlines, such as HTTP write deadlines or
+// database leases, while a large read at a low limit takes its time. fn runs
+// on the reading goroutine, in between sleeps.
+func WithKeepalive(period time.Duration, fn func()) Option {
+	return func(r *RateLimitedReader) {
+		if period <= 0 || fn == nil {
+			return
+		}
+
+		r.keepalive = func(context.Context) { fn() }
+		r.keepalivePeriod = period
+	}
+}
+
+// WithKeepaliveContext is WithKeepalive calling fn with the context of the
+// read, see ReadContext.
+func WithKeepaliveContext(period time.Duration, fn func(ctx context.Context)) Option {
+	return func(r *RateLimitedReader) {
+		if period <= 0 || fn == nil {
+			return
+		}
+
+		r.keepalive = fn
+		r.keepalivePeriod = period
+	}
+}
+
+// WithStopChannel aborts the reader's sleeps once stop is closed, failing
+// reads in flight and after with ErrClosed, for code that can't thread a
+// context through.
+func WithStopChannel(stop <-chan struct{}) Option {
+	return func(r *RateLimitedReader) {
+		r.stopC = stop
+	}
+}
+
+// stopped reports whether the stop channel was closed or the reader was
+// closed.
+func (r *RateLimitedReader) stopped() bool {
+	if r.idleClosed.Load() || r.closed.Load() {
+		return true
+	}
+
+	select {
+	case <-r.stopC:
+		return true
+	case <-r.readDone():
+		return true
+	default:
+		return false
+	}
+}
+
+// context returns the context of the ReadContext in progress, or
+// context.Background.
+func (r *RateLimitedReader) context() context.Context {
+	if ctx := r.readCtx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
+
+// readDone returns the done channel of the ReadContext in progress, nil if
+// it has none or the read waits on read-ahead, whose filling it mustn't end.
+func (r *RateLimitedReader) readDone() <-chan struct{} {
+	if r.readAhead != nil {
+		return nil
+	}
+	return r.context().Done()
 }
 
 func (r *RateLimitedReader) Close() error {
+	r.closed.Store(true)
+	r.Resume()
+	r.release()
+	r.onClose.fire(context.Background(), r)
 	return r.reader.Close()
 }
 
+// release stops what r runs in the background and gives back what it holds,
+// short of closing the underlying reader.
+func (r *RateLimitedReader) release() {
+	r.releaseHighResTimer()
+	if r.idleTimer != nil {
+		r.idleTimer.Stop()
+	}
+	if r.minRateAlert != nil {
+		r.minRateAlert.stop()
+	}
+	if r.readAhead != nil {
+		r.readAhead.close()
+	}
+}
+
 func (r *RateLimitedReader) UpdateLimit(newLimit int64) {
-	r.limit.Store(newLimit)
+	r.limiter.SetLimit(newLimit)
+}
+
+// Limiter returns the pacer governing this reader.
+func (r *RateLimitedReader) Limiter() *Limiter {
+	return r.limiter
+}
+
+// SetPollMode makes Read never sleep: it reads what the limit allows right
+// now, returning (0, nil) when nothing is, and leaves it to the caller to
+// retry at NextReadTime. Meant for select-based or single-threaded
+// schedulers.
+func (r *RateLimitedReader) SetPollMode(enabled bool) {
+	r.pollMode.Store(enabled)
+}
+
+// NextReadTime returns when the limit will allow reading a full interval's
+// budget, which is now or earlier if it already does.
+func (r *RateLimitedReader) NextReadTime() time.Time {
+	limit := r.limiter.iterLimit()
+	if limit <= 0 {
+		return time.Now()
+	}
+
+	return r.limiter.nextTime(limit, limit)
+}
+
+// Throttled returns a channel signaled whenever reading goes from running
+// freely to waiting on the limiter, so producers upstream can apply their own
+// backpressure. Signals not yet received are coalesced.
+func (r *RateLimitedReader) Throttled() <-chan struct{} {
+	return r.throttledC
 }
 
 func (r *RateLimitedReader) GetCurrentIterTotalRead() int64 {
 	return r.iterTotalRead.Load()
 }
+
+// GetCurrentTotalRead returns what the current Read has read so far.
+//
+// Deprecated: use GetCurrentIterTotalRead, which it is the v3 to v6 name
+// of.
+func (r *RateLimitedReader) GetCurrentTotalRead() int64 {
+	return r.GetCurrentIterTotalRead()
+}
@@ -1,12 +1,16 @@
-package v6
+package ratelimitedreader
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
+	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -195,6 +199,468 @@ func TestRateLimitedReadCloser_Close(t *testing.T) {
 	}
 }
 
+func TestNewReaderE(t *testing.T) {
+	if _, err := NewReaderE(bytes.NewReader(nil), 1024); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewReaderE(bytes.NewReader(nil), 0); err != nil {
+		t.Fatalf("unexpected error for no limit: %v", err)
+	}
+	if _, err := NewReaderE(nil, 1024); !errors.Is(err, ErrNilReader) {
+		t.Fatalf("unexpected error for nil reader: %v expected: %v", err, ErrNilReader)
+	}
+	if _, err := NewReaderE(bytes.NewReader(nil), -1); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for negative limit: %v expected: %v", err, ErrInvalidLimit)
+	}
+	if _, err := NewReaderE(bytes.NewReader(nil), 1); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for a limit too small to pace: %v expected: %v", err, ErrInvalidLimit)
+	}
+
+	if _, err := NewReaderE(bytes.NewReader(nil), 1024, WithInterval(2*time.Second)); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for a too long interval: %v expected: %v", err, ErrInvalidLimit)
+	}
+	if _, err := NewReaderE(bytes.NewReader(nil), 10, WithInterval(10*time.Millisecond)); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for a limit too small to pace in the reader's interval: %v expected: %v", err, ErrInvalidLimit)
+	}
+
+	interval := ReadIntervalMilliseconds
+	defer func() { ReadIntervalMilliseconds = interval }()
+	ReadIntervalMilliseconds = 0
+	if _, err := NewReaderE(bytes.NewReader(nil), 1024); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for a zero interval: %v expected: %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestRateLimitedReader_WithInterval(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+	const interval = 200 * time.Millisecond
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithInterval(interval))
+	if ratelimitedReader.Limiter().Interval() != interval {
+		t.Fatalf("unexpected interval: %v expected: %v", ratelimitedReader.Limiter().Interval(), interval)
+	}
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	// an interval's budget of 2048 bytes falls in bucket 11
+	if reads := ratelimitedReader.Stats().ReadSizes[11]; reads != dataSize/2048 {
+		t.Fatalf("unexpected reads of an interval's budget: %d, read sizes: %v", reads, ratelimitedReader.Stats().ReadSizes)
+	}
+}
+
+func TestRateLimitedReader_Throttled(t *testing.T) {
+	const dataSize = 10 * 1024  // 10KB
+	const bufferSize = dataSize // one read call
+	const limit = dataSize * 2  // half a second
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit))
+
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	select {
+	case <-ratelimitedReader.Throttled():
+	default:
+		t.Fatalf("expected a throttled signal after a limited read")
+	}
+
+	select {
+	case <-ratelimitedReader.Throttled():
+		t.Fatalf("expected a single throttled signal for one transition")
+	default:
+	}
+}
+
+func TestRateLimitedReader_NoLimitNotThrottled(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const bufferSize = dataSize // one read call
+	const limit = 0             // no limit
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit)
+
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	select {
+	case <-ratelimitedReader.Throttled():
+		t.Fatalf("unexpected throttled signal without a limit")
+	default:
+	}
+}
+
+func TestRateLimitedReader_TryRead(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit))
+	buffer := make([]byte, bufferSize)
+
+	iterLimit := limit / (1000 / ReadIntervalMilliseconds)
+	start := time.Now()
+	total, err := ratelimitedReader.TryRead(buffer)
+	if err != nil || int64(total) != iterLimit {
+		t.Fatalf("unexpected first try read, read: %d err: %v expected: %d", total, err, iterLimit)
+	}
+
+	if n, err := ratelimitedReader.TryRead(buffer[total:]); err != ErrWouldBlock {
+		t.Fatalf("expected try read without budget to block, read: %d err: %v", n, err)
+	}
+
+	for total < dataSize {
+		n, err := ratelimitedReader.TryRead(buffer[total:])
+		total += n
+		if err == ErrWouldBlock {
+			time.Sleep(time.Millisecond)
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestRateLimitedReader_TryReadNoLimit(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const limit = 0             // no limit
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit)
+
+	n, err := ratelimitedReader.TryRead(make([]byte, dataSize))
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected try read, read: %d err: %v expected: %d", n, err, dataSize)
+	}
+}
+
+func TestRateLimitedReader_PollMode(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit))
+	ratelimitedReader.SetPollMode(true)
+	buffer := make([]byte, bufferSize)
+
+	start := time.Now()
+	total := 0
+	polls := 0
+	for total < dataSize {
+		n, err := ratelimitedReader.Read(buffer[total:])
+		total += n
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if n == 0 {
+			polls++
+			time.Sleep(time.Until(ratelimitedReader.NextReadTime()))
+		}
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if polls > dataSize/int(limit/(1000/ReadIntervalMilliseconds)) {
+		t.Fatalf("polled too often, polls: %d", polls)
+	}
+}
+
+func TestRateLimitedReader_ReadAfterEOF(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+	const readsAfterEOF = 20             // would take a second if each was paced
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit))
+
+	read(t, ratelimitedReader, bufferSize, dataSize)
+
+	start := time.Now()
+	for i := 0; i < readsAfterEOF; i++ {
+		read(t, ratelimitedReader, bufferSize, 0)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestRateLimitedReader_DeferredEOF(t *testing.T) {
+	const dataSize = 1024           // 1KB
+	const bufferSize = dataSize * 2 // large buffer
+	const limit = dataSize * 20     // large limit
+
+	ratelimitedReader := NewRateLimitedReader(iotest.DataErrReader(bytes.NewReader(make([]byte, dataSize))), int64(limit))
+	n, err := ratelimitedReader.Read(make([]byte, bufferSize))
+	if n != dataSize || err != io.EOF {
+		t.Fatalf("expected %d bytes with EOF, got %d bytes with error: %v", dataSize, n, err)
+	}
+
+	ratelimitedReader = NewRateLimitedReader(iotest.DataErrReader(bytes.NewReader(make([]byte, dataSize))), int64(limit), WithDeferredEOF())
+	n, err = ratelimitedReader.Read(make([]byte, bufferSize))
+	if n != dataSize || err != nil {
+		t.Fatalf("expected %d bytes with no error, got %d bytes with error: %v", dataSize, n, err)
+	}
+	n, err = ratelimitedReader.Read(make([]byte, bufferSize))
+	if n != 0 || err != io.EOF {
+		t.Fatalf("expected EOF on the next read, got %d bytes with error: %v", n, err)
+	}
+}
+
+func TestRateLimitedReader_TransferError(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const limit = dataSize * 2  // half a second
+
+	errBroken := errors.New("broken stream")
+	reader := io.MultiReader(bytes.NewReader(make([]byte, dataSize)), iotest.ErrReader(errBroken))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit))
+
+	_, err := io.ReadAll(ratelimitedReader)
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("expected the underlying error, got: %v", err)
+	}
+
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) {
+		t.Fatalf("expected a TransferError, got %T", err)
+	}
+	if transferErr.BytesRead != dataSize || transferErr.Limit != limit {
+		t.Fatalf("unexpected transfer context, bytes read: %d limit: %d expected: %d %d",
+			transferErr.BytesRead, transferErr.Limit, dataSize, limit)
+	}
+	if transferErr.Elapsed < 400*time.Millisecond {
+		t.Fatalf("elapsed too short: %v", transferErr.Elapsed)
+	}
+}
+
+func TestRateLimitedReader_PartialOnTimeout(t *testing.T) {
+	const dataSize = 4 * 1024       // 4KB
+	const bufferSize = dataSize * 2 // more than the source has before timing out
+	const limit = dataSize * 10
+
+	for _, pacing := range []Pacing{PaceBeforeRead, PaceAfterRead} {
+		reader := io.MultiReader(bytes.NewReader(make([]byte, dataSize)), iotest.ErrReader(os.ErrDeadlineExceeded))
+		ratelimitedReader := NewRateLimitedReader(reader, limit, WithPacing(pacing), WithPartialOnTimeout())
+
+		// the data gathered before the timeout comes without it
+		buf := make([]byte, bufferSize)
+		n, err := ratelimitedReader.Read(buf)
+		if n != dataSize || err != nil {
+			t.Fatalf("unexpected partial read: %d error: %v expected: %d", n, err, dataSize)
+		}
+
+		// and the timeout with the next read
+		n, err = ratelimitedReader.Read(buf)
+		var transferErr *TransferError
+		if n != 0 || !errors.As(err, &transferErr) || !IsTimeout(err) {
+			t.Fatalf("unexpected read after the timeout: %d error: %v", n, err)
+		}
+		if transferErr.BytesRead != dataSize {
+			t.Fatalf("unexpected bytes read: %d expected: %d", transferErr.BytesRead, dataSize)
+		}
+	}
+
+	// other errors come along with the data
+	reader := io.MultiReader(bytes.NewReader(make([]byte, dataSize)), iotest.ErrReader(io.ErrUnexpectedEOF))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithPartialOnTimeout())
+	if n, err := ratelimitedReader.Read(make([]byte, bufferSize)); n != dataSize || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected read: %d error: %v", n, err)
+	}
+}
+
+func TestRateLimitedReader_StopChannel(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const bufferSize = dataSize // one read call
+	const limit = dataSize / 5  // five seconds
+
+	stop := make(chan struct{})
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithStopChannel(stop))
+
+	time.AfterFunc(200*time.Millisecond, func() { close(stop) })
+
+	start := time.Now()
+	n, err := ratelimitedReader.Read(make([]byte, bufferSize))
+	if err != ErrClosed {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrClosed)
+	}
+	if n == 0 || n == dataSize {
+		t.Fatalf("expected a partial read, read: %d", n)
+	}
+	assertReadTimes(t, time.Since(start), 0, 0)
+
+	if _, err := ratelimitedReader.Read(make([]byte, bufferSize)); err != ErrClosed {
+		t.Fatalf("unexpected error after stop: %v expected: %v", err, ErrClosed)
+	}
+}
+
+func TestRateLimitedReader_SetIntervalLive(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const bufferSize = dataSize
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithInterval(time.Second))
+
+	// switch to smoother pacing in the middle of the first second's grant
+	time.AfterFunc(300*time.Millisecond, func() { ratelimitedReader.SetInterval(50 * time.Millisecond) })
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	// 50ms grants of 1024 bytes fall in bucket 10
+	if reads := ratelimitedReader.Stats().ReadSizes[10]; reads == 0 {
+		t.Fatalf("expected reads in the new interval's steps, read sizes: %v", ratelimitedReader.Stats().ReadSizes)
+	}
+}
+
+func TestRateLimitedReader_CloseDuringSleep(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const bufferSize = dataSize
+	const limit = dataSize / 4 // a four second read
+	const sleepSlice = 50 * time.Millisecond
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit, WithSleepSlice(sleepSlice))
+
+	time.AfterFunc(500*time.Millisecond, func() { ratelimitedReader.Close() })
+	start := time.Now()
+	_, err := ratelimitedReader.Read(make([]byte, bufferSize))
+	if err != ErrClosed {
+		t.Fatalf("unexpected error after close: %v expected: %v", err, ErrClosed)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond+2*sleepSlice {
+		t.Fatalf("close noticed too late, elapsed time: %v", elapsed)
+	}
+}
+
+func TestRateLimitedReader_LimitChangeDuringSleep(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize
+	const limit = dataSize / 8 // an eight second read at first
+
+	// readers sleep for a whole read when the interval matches it
+	oldInterval := ReadIntervalMilliseconds
+	ReadIntervalMilliseconds = 1000
+	defer func() { ReadIntervalMilliseconds = oldInterval }()
+
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, limit)
+
+	time.AfterFunc(500*time.Millisecond, func() { ratelimitedReader.UpdateLimit(dataSize * 4) })
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	// the first grant alone would take a second without rebooking
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Fatalf("limit change noticed too late, elapsed time: %v", elapsed)
+	}
+}
+
+func TestRateLimitedReader_Keepalive(t *testing.T) {
+	const dataSize = 40 * 1024  // 40KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 2
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+	const keepalivePeriod = 200 * time.Millisecond
+
+	var keepalives int
+	reader := bytes.NewReader(make([]byte, dataSize))
+	ratelimitedReader := NewRateLimitedReader(reader, int64(limit), WithKeepalive(keepalivePeriod, func() { keepalives++ }))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	expected := int(partsAmount * time.Second / keepalivePeriod)
+	if keepalives < expected-2 || keepalives > expected {
+		t.Fatalf("unexpected keepalives amount: %d expected about: %d", keepalives, expected)
+	}
+}
+
+func TestNewReaderWithLimiter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 10
+	const readersAmount = 2
+	const limit = dataSize // both readers together take readersAmount seconds
+
+	limiter := NewLimiter(limit)
+	readers := []*RateLimitedReader{
+		NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), limiter),
+		NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), limiter),
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	for _, reader := range readers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			read(t, reader, bufferSize, dataSize)
+		}()
+	}
+	for range readers {
+		<-done
+	}
+	assertReadTimes(t, time.Since(start), readersAmount, readersAmount)
+}
+
+func TestNewFromSame(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const bufferSize = dataSize / 10
+	const partsAmount = 2
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	data := make([]byte, dataSize)
+	rand.Read(data)
+	h := sha256.New()
+
+	// drop the stream halfway and reconnect to the rest
+	dropped := NewRateLimitedReader(bytes.NewReader(data[:dataSize/2]), limit, WithHash(h))
+	start := time.Now()
+	read(t, dropped, bufferSize, dataSize/2)
+
+	reconnected := NewFromSame(dropped, bytes.NewReader(data[dataSize/2:]))
+	if reconnected.Limiter() != dropped.Limiter() {
+		t.Fatalf("reconnected reader doesn't share the limiter")
+	}
+	read(t, reconnected, bufferSize, dataSize/2)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	expected := sha256.Sum256(data)
+	if !bytes.Equal(h.Sum(nil), expected[:]) {
+		t.Fatalf("hash doesn't cover both streams")
+	}
+}
+
+func TestRateLimitedReader_AdoptState(t *testing.T) {
+	const limit = 10 * 1024
+	const burstSize = limit
+
+	opts := []Option{WithQuota(burstSize*3, time.Hour), WithBorrowing(burstSize, 0.5)}
+	dropped := NewRateLimitedReader(bytes.NewReader(make([]byte, burstSize)), limit, opts...)
+	read(t, dropped, burstSize, burstSize)
+
+	// a reconnect owes what the dropped stream borrowed, repaying it at half
+	// the limit, rather than borrowing anew
+	reconnected := NewRateLimitedReader(bytes.NewReader(make([]byte, burstSize*2)), limit, opts...)
+	reconnected.AdoptState(dropped)
+	start := time.Now()
+	read(t, reconnected, burstSize, burstSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+
+	// and has only what's left of the quota
+	read(t, reconnected, burstSize, burstSize)
+	if _, err := reconnected.Read(make([]byte, burstSize)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrQuotaExceeded)
+	}
+}
+
 func TestRateLimitedReader_ReadUnstableStream(t *testing.T) {
 	const dataSize = 32 * 1024 // 32KB buffer
 	const bufferSize = 1024    // small buffer
@@ -0,0 +1,127 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// TraceEvent is one read of a recorded transfer: N bytes returned At after
+// the first read started.
+type TraceEvent struct {
+	At time.Duration
+	N  int
+}
+
+// Trace is the timing of a transfer's reads, as recorded by a Recorder.
+type Trace []TraceEvent
+
+// Bytes returns how many bytes the trace transferred.
+func (t Trace) Bytes() int64 {
+	var total int64
+	for _, event := range t {
+		total += int64(event.N)
+	}
+	return total
+}
+
+// Duration returns when the trace's last read returned.
+func (t Trace) Duration() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].At
+}
+
+// Recorder passes reads through unchanged while recording their timing, to
+// capture real-world transfers for Replay.
+type Recorder struct {
+	reader io.Reader
+
+	mu    sync.Mutex
+	start time.Time
+	trace Trace
+}
+
+func NewRecorder(reader io.Reader) *Recorder {
+	return &Recorder{reader: reader}
+}
+
+func (r *Recorder) Read(p []byte) (n int, err error) {
+	r.mu.Lock()
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	r.mu.Unlock()
+
+	n, err = r.reader.Read(p)
+	if n > 0 {
+		r.mu.Lock()
+		r.trace = append(r.trace, TraceEvent{At: time.Since(r.start), N: n})
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+// Trace returns the reads recorded so far.
+func (r *Recorder) Trace() Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append(Trace(nil), r.trace...)
+}
+
+// Reader returns a reader reproducing the trace's timing as a source: each
+// event's bytes, zeros, become readable At after the first read, and the
+// reader returns io.EOF after the last.
+func (t Trace) Reader() io.Reader {
+	return &traceReader{trace: t}
+}
+
+type traceReader struct {
+	trace Trace
+	start time.Time
+	// left is what's left of trace[0] to read
+	left int
+}
+
+func (r *traceReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	if len(r.trace) == 0 {
+		return 0, io.EOF
+	}
+
+	event := r.trace[0]
+	if r.left == 0 {
+		r.left = event.N
+		time.Sleep(time.Until(r.start.Add(event.At)))
+	}
+
+	n := min(len(p), r.left)
+	clear(p[:n])
+	r.left -= n
+	if r.left == 0 {
+		r.trace = r.trace[1:]
+	}
+	return n, nil
+}
+
+// Replay re-runs a recorded transfer through a reader limited to limit, read
+// with bufferSize buffers, and returns the delivered trace, so pacing changes
+// can be checked against real-world source timing. It runs in real time,
+// taking about as long as the transfer would.
+func Replay(trace Trace, limit int64, bufferSize int, opts ...Option) (Trace, error) {
+	recorder := NewRecorder(NewRateLimitedReader(trace.Reader(), limit, opts...))
+	buf := make([]byte, bufferSize)
+	for {
+		_, err := recorder.Read(buf)
+		if err == io.EOF {
+			return recorder.Trace(), nil
+		}
+		if err != nil {
+			return recorder.Trace(), err
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ratelimitedreader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	// a source delivering it all in the first half second
+	var trace Trace
+	for i := range 4 {
+		trace = append(trace, TraceEvent{At: time.Duration(i) * 125 * time.Millisecond, N: dataSize / 4})
+	}
+
+	delivered, err := Replay(trace, limit, dataSize/10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered.Bytes() != dataSize {
+		t.Fatalf("unexpected delivered bytes: %d expected: %d", delivered.Bytes(), dataSize)
+	}
+	assertReadTimes(t, delivered.Duration(), partsAmount, partsAmount)
+}
+
+func TestRecorder_SourceTiming(t *testing.T) {
+	const eventSize = 1024
+	const gap = 200 * time.Millisecond
+
+	trace := Trace{{At: 0, N: eventSize}, {At: gap, N: eventSize}}
+	recorder := NewRecorder(trace.Reader())
+	buf := make([]byte, eventSize*2)
+	for {
+		if _, err := recorder.Read(buf); err != nil {
+			break
+		}
+	}
+
+	recorded := recorder.Trace()
+	if len(recorded) != 2 || recorded.Bytes() != eventSize*2 {
+		t.Fatalf("unexpected recorded trace: %v", recorded)
+	}
+	if at := recorded[1].At; at < gap || at > gap+50*time.Millisecond {
+		t.Fatalf("unexpected replayed timing: %v expected about: %v", at, gap)
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryMinBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 10 * time.Second
+)
+
+// RetryPolicy decides which errors from the underlying reader WithRetry
+// retries, and how.
+type RetryPolicy struct {
+	// Retryable reports whether err is transient and the read worth
+	// retrying, IsTimeout if nil. io.EOF is never retried.
+	Retryable func(err error) bool
+
+	// Attempts is how many times a failing read is retried before its error
+	// is returned, 3 if 0 or below.
+	Attempts int
+
+	// MinBackoff is the backoff before the first retry, 100ms if 0, doubled
+	// on each following one up to MaxBackoff, 10s if 0. Each backoff is
+	// jittered down by up to half so retrying readers spread out.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// WithRetry retries reads from the underlying reader that fail with errors
+// policy classifies as transient, for flaky network sources. The retry reuses
+// the budget already paced for the failed read instead of booking more, and
+// the backoff is slept in sleep slices like the limiter's waits, so Close and
+// the stop channel cut it short. It doesn't count as throttled.
+func WithRetry(policy RetryPolicy) Option {
+	return func(r *RateLimitedReader) {
+		if policy.Retryable == nil {
+			policy.Retryable = IsTimeout
+		}
+		if policy.Attempts <= 0 {
+			policy.Attempts = defaultRetryAttempts
+		}
+		if policy.MinBackoff <= 0 {
+			policy.MinBackoff = defaultRetryMinBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaultRetryMaxBackoff
+		}
+		r.retry = &policy
+	}
+}
+
+// readSource reads from the underlying reader, retrying transient errors
+// with backoff. A read that got data before failing returns the data
+// without the error, leaving it to the next read to hit it again.
+func (r *RateLimitedReader) readSource(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if r.retry == nil {
+		return n, err
+	}
+
+	backoff := r.retry.MinBackoff
+	for attempt := 0; err != nil && err != io.EOF && r.retry.Retryable(err); attempt++ {
+		if n > 0 {
+			return n, nil
+		}
+		if attempt == r.retry.Attempts {
+			break
+		}
+
+		jittered := backoff - rand.N(backoff/2+1)
+		if waitErr := r.backoff(jittered); waitErr != nil {
+			return 0, waitErr
+		}
+		backoff = min(backoff*2, r.retry.MaxBackoff)
+
+		n, err = r.reader.Read(p)
+	}
+	return n, err
+}
+
+// backoff sleeps for d before a retry, in slices of at most sleepSlice,
+// returning ErrClosed if closed or stopped first.
+func (r *RateLimitedReader) backoff(d time.Duration) error {
+	wakeAt := time.Now().Add(d)
+	for {
+		left := time.Until(wakeAt)
+		if left <= 0 {
+			return nil
+		}
+		if r.stopped() {
+			return ErrClosed
+		}
+
+		if r.sleepSlice > 0 {
+			left = min(left, r.sleepSlice)
+		}
+		if err := r.sleepUnlessStopped(left); err != nil {
+			return err
+		}
+	}
+}
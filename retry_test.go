@@ -0,0 +1,128 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+// flakyReader fails its first failures reads with err.
+type flakyReader struct {
+	reader   io.Reader
+	failures int
+	err      error
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.failures > 0 {
+		f.failures--
+		return 0, f.err
+	}
+	return f.reader.Read(p)
+}
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestRateLimitedReader_Retry(t *testing.T) {
+	const dataSize = 1024
+	const bufferSize = 256
+	const limit = dataSize * 10
+
+	source := &flakyReader{reader: bytes.NewReader(make([]byte, dataSize)), failures: 2, err: errTransient}
+	ratelimitedReader := NewRateLimitedReader(source, limit, WithRetry(RetryPolicy{
+		Retryable:  isTransient,
+		MinBackoff: 100 * time.Millisecond,
+	}))
+
+	// backs off at least half of 100ms and 200ms
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("retried without backing off, took: %v", elapsed)
+	}
+}
+
+func TestRateLimitedReader_RetryGivesUp(t *testing.T) {
+	const limit = 1024
+
+	for _, tc := range []struct {
+		name   string
+		source *flakyReader
+	}{
+		{"attempts used up", &flakyReader{reader: bytes.NewReader(nil), failures: 3, err: errTransient}},
+		{"not retryable", &flakyReader{reader: bytes.NewReader(nil), failures: 1, err: io.ErrUnexpectedEOF}},
+	} {
+		ratelimitedReader := NewRateLimitedReader(tc.source, limit, WithRetry(RetryPolicy{
+			Retryable:  isTransient,
+			Attempts:   2,
+			MinBackoff: time.Millisecond,
+		}))
+
+		if _, err := ratelimitedReader.Read(make([]byte, limit)); !errors.Is(err, tc.source.err) {
+			t.Fatalf("%s: unexpected error: %v expected: %v", tc.name, err, tc.source.err)
+		}
+	}
+}
+
+func TestRateLimitedReader_CloseDuringRetry(t *testing.T) {
+	const limit = 1024
+
+	source := &flakyReader{reader: bytes.NewReader(nil), failures: 1, err: errTransient}
+	ratelimitedReader := NewRateLimitedReadCloser(io.NopCloser(source), limit, WithRetry(RetryPolicy{
+		Retryable:  isTransient,
+		MinBackoff: 10 * time.Second,
+	}))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ratelimitedReader.Close()
+	}()
+
+	start := time.Now()
+	if _, err := ratelimitedReader.Read(make([]byte, limit)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrClosed)
+	}
+	assertReadTimes(t, time.Since(start), 0, 1)
+}
+
+// timeoutError is a net.Error style timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestRateLimitedReader_RetryDefaults(t *testing.T) {
+	const dataSize = 1024
+	const limit = dataSize * 10
+
+	for _, tc := range []struct {
+		name   string
+		policy RetryPolicy
+	}{
+		{"zero policy", RetryPolicy{}},
+		{"negative attempts", RetryPolicy{Retryable: IsTimeout, Attempts: -1, MinBackoff: time.Millisecond}},
+	} {
+		// timeouts are retried by default, up to the default attempts
+		source := &flakyReader{reader: bytes.NewReader(make([]byte, dataSize)), failures: defaultRetryAttempts, err: timeoutError{}}
+		ratelimitedReader := NewRateLimitedReader(source, limit, WithRetry(tc.policy))
+		read(t, ratelimitedReader, dataSize, dataSize)
+
+		source = &flakyReader{reader: bytes.NewReader(nil), failures: defaultRetryAttempts + 1, err: timeoutError{}}
+		ratelimitedReader = NewRateLimitedReader(source, limit, WithRetry(tc.policy))
+		if _, err := ratelimitedReader.Read(make([]byte, dataSize)); !errors.Is(err, timeoutError{}) {
+			t.Fatalf("%s: unexpected error: %v expected: %v", tc.name, err, timeoutError{})
+		}
+
+		source = &flakyReader{reader: bytes.NewReader(nil), failures: 1, err: errTransient}
+		ratelimitedReader = NewRateLimitedReader(source, limit, WithRetry(tc.policy))
+		if _, err := ratelimitedReader.Read(make([]byte, dataSize)); !errors.Is(err, errTransient) {
+			t.Fatalf("%s: unexpected error: %v expected: %v", tc.name, err, errTransient)
+		}
+	}
+}
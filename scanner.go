@@ -0,0 +1,140 @@
+package ratelimitedreader
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+)
+
+// ReadByte reads a single byte at the limited rate, making the reader an
+// io.ByteScanner so byte-oriented parsers can sit on it directly without a
+// bufio layer reading ahead of the pacing. In poll mode it returns
+// ErrWouldBlock instead of waiting.
+func (r *RateLimitedReader) ReadByte() (byte, error) {
+	var b [1]byte
+	for {
+		n, err := r.Read(b[:])
+		if n == 1 {
+			r.lastByte = b[0]
+			r.hasLastByte = true
+			return b[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if r.pollMode.Load() {
+			return 0, ErrWouldBlock
+		}
+	}
+}
+
+// UnreadByte makes the next read return the byte last read by ReadByte
+// again, without pacing it twice.
+func (r *RateLimitedReader) UnreadByte() error {
+	if !r.hasLastByte || r.byteUnread {
+		return bufio.ErrInvalidUnreadByte
+	}
+
+	r.byteUnread = true
+	return nil
+}
+
+// readUnreadByte fills p with the byte given back by UnreadByte, if any.
+// Other reads make UnreadByte invalid until the next ReadByte.
+func (r *RateLimitedReader) readUnreadByte(p []byte) (int, bool) {
+	if !r.byteUnread {
+		r.hasLastByte = false
+		return 0, false
+	}
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	r.byteUnread = false
+	p[0] = r.lastByte
+	return 1, true
+}
+
+// Scanner mirrors bufio.Scanner, delivering each token at the limited rate
+// rather than as fast as the source reads, for tools that replay logs or
+// other records at a controlled speed. The limit is either bytes or tokens
+// per second; as with bufio.Scanner, a token's delimiter isn't part of it
+// and isn't paced in bytes mode.
+type Scanner struct {
+	scanner *bufio.Scanner
+	limiter *Limiter
+	// perToken paces one unit per token instead of its length
+	perToken bool
+}
+
+// NewScanner returns a Scanner splitting lines out of reader and delivering
+// them at limit bytes per second.
+func NewScanner(reader io.Reader, limit int64) *Scanner {
+	return &Scanner{
+		scanner: bufio.NewScanner(reader),
+		limiter: NewLimiter(limit),
+	}
+}
+
+// NewTokenScanner returns a Scanner splitting lines out of reader and
+// delivering them at limit tokens per second, whatever their lengths.
+func NewTokenScanner(reader io.Reader, limit int64) *Scanner {
+	limiter := NewLimiter(limit)
+	// a second's budget, so even single-digit token rates have one to grant
+	limiter.SetInterval(time.Second)
+	return &Scanner{
+		scanner:  bufio.NewScanner(reader),
+		limiter:  limiter,
+		perToken: true,
+	}
+}
+
+// Scan advances to the next token like bufio.Scanner.Scan, blocking until
+// the limit allows delivering it.
+func (s *Scanner) Scan() bool {
+	if !s.scanner.Scan() {
+		return false
+	}
+
+	n := len(s.scanner.Bytes())
+	if s.perToken {
+		n = 1
+	}
+	if n > 0 {
+		s.limiter.WaitN(context.Background(), n)
+	}
+	return true
+}
+
+func (s *Scanner) Bytes() []byte {
+	return s.scanner.Bytes()
+}
+
+func (s *Scanner) Text() string {
+	return s.scanner.Text()
+}
+
+func (s *Scanner) Err() error {
+	return s.scanner.Err()
+}
+
+// Buffer sets the buffer to scan with, as bufio.Scanner.Buffer does.
+func (s *Scanner) Buffer(buf []byte, max int) {
+	s.scanner.Buffer(buf, max)
+}
+
+// Split sets the split function, bufio.ScanLines by default, as
+// bufio.Scanner.Split does.
+func (s *Scanner) Split(split bufio.SplitFunc) {
+	s.scanner.Split(split)
+}
+
+func (s *Scanner) UpdateLimit(newLimit int64) {
+	s.limiter.SetLimit(newLimit)
+}
+
+// Limiter returns the pacer governing this scanner.
+func (s *Scanner) Limiter() *Limiter {
+	return s.limiter
+}
@@ -0,0 +1,110 @@
+package ratelimitedreader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _ io.ByteScanner = (*RateLimitedReader)(nil)
+
+func TestRateLimitedReader_ReadByte(t *testing.T) {
+	const dataSize = 400
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit)
+
+	start := time.Now()
+	for i := 0; i < dataSize; i++ {
+		if _, err := ratelimitedReader.ReadByte(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if _, err := ratelimitedReader.ReadByte(); err != io.EOF {
+		t.Fatalf("unexpected error at the end: %v expected: %v", err, io.EOF)
+	}
+}
+
+func TestRateLimitedReader_UnreadByte(t *testing.T) {
+	ratelimitedReader := NewRateLimitedReader(strings.NewReader("hello"), 0)
+
+	if err := ratelimitedReader.UnreadByte(); err != bufio.ErrInvalidUnreadByte {
+		t.Fatalf("unexpected error before reading: %v expected: %v", err, bufio.ErrInvalidUnreadByte)
+	}
+
+	b, _ := ratelimitedReader.ReadByte()
+	if err := ratelimitedReader.UnreadByte(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := ratelimitedReader.ReadByte(); again != b {
+		t.Fatalf("unexpected byte after unread: %q expected: %q", again, b)
+	}
+
+	ratelimitedReader.UnreadByte()
+	data, err := io.ReadAll(ratelimitedReader)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected data: %q error: %v", data, err)
+	}
+	if err := ratelimitedReader.UnreadByte(); err != bufio.ErrInvalidUnreadByte {
+		t.Fatalf("unexpected error after read: %v expected: %v", err, bufio.ErrInvalidUnreadByte)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	const lineSize = 1023 // 1KB with the newline
+	const linesAmount = 20
+	const partsAmount = 2
+	const limit = lineSize * linesAmount / partsAmount // lineSize*linesAmount/partsAmount bytes per second
+
+	line := strings.Repeat("a", lineSize)
+	scanner := NewScanner(strings.NewReader(strings.Repeat(line+"\n", linesAmount)), limit)
+
+	start := time.Now()
+	lines := 0
+	for scanner.Scan() {
+		if scanner.Text() != line {
+			t.Fatalf("unexpected line: %q", scanner.Text())
+		}
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines != linesAmount {
+		t.Fatalf("unexpected lines: %d expected: %d", lines, linesAmount)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestTokenScanner(t *testing.T) {
+	const tokensAmount = 10
+	const partsAmount = 2
+	const limit = tokensAmount / partsAmount // tokensAmount/partsAmount tokens per second
+
+	// tokens of any length cost the same
+	var data strings.Builder
+	for i := range tokensAmount {
+		data.WriteString(strings.Repeat("a", i*1024) + "\n")
+	}
+	scanner := NewTokenScanner(strings.NewReader(data.String()), limit)
+	scanner.Buffer(nil, tokensAmount*1024)
+
+	start := time.Now()
+	tokens := 0
+	for scanner.Scan() {
+		tokens++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokens != tokensAmount {
+		t.Fatalf("unexpected tokens: %d expected: %d", tokens, tokensAmount)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
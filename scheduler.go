@@ -0,0 +1,155 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Scheduler books bandwidth windows ahead of time against a link's capacity,
+// the limit of a shared Limiter, for orchestrating scheduled transfers such
+// as backups: "5MB/s from 02:00 to 04:00". Windows that would together
+// exceed the capacity at any time are refused.
+type Scheduler struct {
+	capacity *Limiter
+
+	mu      sync.Mutex
+	windows []*Window
+}
+
+// Window is bandwidth booked on a Scheduler, handing out readers paced at
+// its rate while it's open.
+type Window struct {
+	scheduler  *Scheduler
+	start, end time.Time
+	rate       int64
+
+	// limiter is shared by the window's readers, and closing stop ends
+	// them at the window's end
+	limiter *Limiter
+	stop    chan struct{}
+	timer   *time.Timer
+}
+
+// NewScheduler returns a scheduler booking windows against capacity's limit.
+func NewScheduler(capacity *Limiter) *Scheduler {
+	return &Scheduler{capacity: capacity}
+}
+
+// Reserve books rate bytes per second from start until end. It returns
+// ErrInvalidLimit for an empty window or a rate that isn't positive, and
+// ErrOverbooked if the windows already booked leave less than rate free at
+// some time in between.
+func (s *Scheduler) Reserve(start, end time.Time, rate int64) (*Window, error) {
+	if !end.After(start) || rate <= 0 {
+		return nil, fmt.Errorf("%w: window of %d from %v to %v", ErrInvalidLimit, rate, start, end)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if booked := s.peak(start, end); booked+rate > s.capacity.Limit() {
+		return nil, fmt.Errorf("%w: %d booked of %d", ErrOverbooked, booked, s.capacity.Limit())
+	}
+
+	w := &Window{
+		scheduler: s,
+		start:     start,
+		end:       end,
+		rate:      rate,
+		limiter:   NewLimiter(rate),
+		stop:      make(chan struct{}),
+	}
+	w.timer = time.AfterFunc(time.Until(end), w.close)
+	s.windows = append(s.windows, w)
+	return w, nil
+}
+
+// peak returns the most bandwidth booked at once between start and end.
+// Must hold s.mu.
+func (s *Scheduler) peak(start, end time.Time) int64 {
+	// the total booked only changes where a window starts
+	times := []time.Time{start}
+	for _, w := range s.windows {
+		if w.start.After(start) && w.start.Before(end) {
+			times = append(times, w.start)
+		}
+	}
+
+	var peak int64
+	for _, t := range times {
+		var booked int64
+		for _, w := range s.windows {
+			if !w.start.After(t) && w.end.After(t) {
+				booked += w.rate
+			}
+		}
+		peak = max(peak, booked)
+	}
+	return peak
+}
+
+// Open waits until the window opens, then returns a reader of reader paced
+// at the window's rate, shared with the window's other readers. Under that
+// it draws from the capacity limiter too, so the windows and other traffic
+// on the capacity limiter together stay within the link. Reads fail with
+// ErrClosed once the window ends. Open fails with ErrClosed after the window
+// ended or was canceled, and with ctx's error if it's done first.
+func (w *Window) Open(ctx context.Context, reader io.Reader, opts ...Option) (*RateLimitedReader, error) {
+	timer := time.NewTimer(time.Until(w.start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.stop:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case <-w.stop:
+		return nil, ErrClosed
+	default:
+	}
+
+	capped := NewReaderWithLimiter(reader, w.scheduler.capacity, WithStopChannel(w.stop))
+	opts = append(opts[:len(opts):len(opts)], WithStopChannel(w.stop))
+	return NewReadCloserWithLimiter(capped, w.limiter, opts...), nil
+}
+
+// Cancel gives the window's bandwidth back to the scheduler and ends its
+// readers.
+func (w *Window) Cancel() {
+	w.timer.Stop()
+	w.close()
+}
+
+// close ends the window, once.
+func (w *Window) close() {
+	s := w.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.windows, w)
+	if i < 0 {
+		return
+	}
+	s.windows = slices.Delete(s.windows, i, i+1)
+	close(w.stop)
+}
+
+func (w *Window) Start() time.Time {
+	return w.start
+}
+
+func (w *Window) End() time.Time {
+	return w.end
+}
+
+func (w *Window) Rate() int64 {
+	return w.rate
+}
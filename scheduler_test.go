@@ -0,0 +1,112 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Reserve(t *testing.T) {
+	const capacity = 10 * 1024
+
+	scheduler := NewScheduler(NewLimiter(capacity))
+	now := time.Now()
+	hour := func(h int) time.Time { return now.Add(time.Duration(h) * time.Hour) }
+
+	first, err := scheduler.Reserve(hour(2), hour(4), capacity/2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := scheduler.Reserve(hour(3), hour(5), capacity/2); err != nil {
+		t.Fatalf("unexpected error for a window fitting next to another: %v", err)
+	}
+
+	// 03:00 to 04:00 is fully booked
+	if _, err := scheduler.Reserve(hour(1), hour(6), 1); !errors.Is(err, ErrOverbooked) {
+		t.Fatalf("unexpected error: %v expected: %v", err, ErrOverbooked)
+	}
+	if _, err := scheduler.Reserve(hour(5), hour(6), capacity); err != nil {
+		t.Fatalf("unexpected error for a window after the others: %v", err)
+	}
+
+	first.Cancel()
+	if _, err := scheduler.Reserve(hour(2), hour(3), capacity/2); err != nil {
+		t.Fatalf("unexpected error for bandwidth canceled: %v", err)
+	}
+
+	if _, err := scheduler.Reserve(hour(2), hour(2), 1); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("unexpected error for an empty window: %v expected: %v", err, ErrInvalidLimit)
+	}
+}
+
+func TestWindow_Open(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const rate = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	scheduler := NewScheduler(NewLimiter(rate * 2))
+	start := time.Now()
+	window, err := scheduler.Reserve(start.Add(time.Second), start.Add(3*time.Second), rate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// waits for the window to open, then reads at its rate
+	ratelimitedReader, err := window.Open(context.Background(), bytes.NewReader(make([]byte, dataSize)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount+1, partsAmount+2)
+
+	// the window's end cuts reads short
+	endless, err := window.Open(context.Background(), infiniteReader{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, endless); !errors.Is(err, ErrClosed) {
+		t.Fatalf("unexpected error at the window's end: %v expected: %v", err, ErrClosed)
+	}
+	assertReadTimes(t, time.Since(start), 3, 3)
+
+	if _, err := window.Open(context.Background(), infiniteReader{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("unexpected error opening an ended window: %v expected: %v", err, ErrClosed)
+	}
+}
+
+func TestWindow_OpenSharesCapacity(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const capacity = dataSize // a second for each reader's data alone
+
+	limiter := NewLimiter(capacity)
+	scheduler := NewScheduler(limiter)
+	window, err := scheduler.Reserve(time.Now(), time.Now().Add(time.Minute), capacity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer window.Cancel()
+
+	windowed, err := window.Open(context.Background(), bytes.NewReader(make([]byte, dataSize)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outside := NewReaderWithLimiter(bytes.NewReader(make([]byte, dataSize)), limiter)
+
+	// traffic outside the windows on the capacity limiter shares the link
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		read(t, outside, bufferSize, dataSize)
+	}()
+	read(t, windowed, bufferSize, dataSize)
+	<-done
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
@@ -0,0 +1,19 @@
+package ratelimitedreader
+
+import "io"
+
+// Split returns readers over parts that draw from r's budget, so fanning one
+// download out into parallel part streams keeps to the single limit r was
+// given, which UpdateLimit keeps changing for all of them. Parts that are
+// io.ReadClosers are closed with their reader.
+func (r *RateLimitedReader) Split(parts ...io.Reader) []*RateLimitedReader {
+	readers := make([]*RateLimitedReader, len(parts))
+	for i, part := range parts {
+		readCloser, ok := part.(io.ReadCloser)
+		if !ok {
+			readCloser = io.NopCloser(part)
+		}
+		readers[i] = newRateLimitedReadCloser(readCloser, r.limiter)
+	}
+	return readers
+}
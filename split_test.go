@@ -0,0 +1,50 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Split(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 4
+	const partSize = dataSize / partsAmount
+	const bufferSize = partSize / 10
+	const limit = dataSize / 2 // all parts together take two seconds
+
+	parent := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	parts := parent.Split(
+		bytes.NewReader(make([]byte, partSize)),
+		bytes.NewReader(make([]byte, partSize)),
+		bytes.NewReader(make([]byte, partSize)),
+		bytes.NewReader(make([]byte, partSize)),
+	)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, part := range parts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(t, part, bufferSize, partSize)
+		}()
+	}
+	wg.Wait()
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
+
+func TestRateLimitedReader_SplitUpdateLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 10
+	const limit = dataSize / 4
+
+	parent := NewRateLimitedReader(bytes.NewReader(nil), limit)
+	part := parent.Split(bytes.NewReader(make([]byte, dataSize)))[0]
+	parent.UpdateLimit(dataSize)
+
+	start := time.Now()
+	read(t, part, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+}
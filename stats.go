@@ -0,0 +1,109 @@
+package ratelimitedreader
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of a RateLimitedReader's state. Its JSON field names
+// are stable, for shipping snapshots to telemetry pipelines.
+type Stats struct {
+	Limit int64 `json:"limit"`
+
+	// BufferSize is the read-ahead buffer's size and BufferedBytes how much
+	// of it is filled, both 0 without read-ahead.
+	BufferSize    int `json:"buffer_size"`
+	BufferedBytes int `json:"buffered_bytes"`
+
+	// BufferSizes counts the buffer sizes Read was called with and ReadSizes
+	// the sizes the underlying reader's reads completed with.
+	BufferSizes Histogram `json:"buffer_sizes"`
+	ReadSizes   Histogram `json:"read_sizes"`
+
+	// ReturnedSizes counts the sizes Read returned with, leaving out reads
+	// that returned nothing. Throttling can return less than asked for; a
+	// spread to lower buckets than BufferSizes shows how much, for
+	// consumers whose framing suffers from short reads.
+	ReturnedSizes Histogram `json:"returned_sizes"`
+
+	// SourceBytes is how much was read from the underlying reader and
+	// DeliveredBytes how much was handed to callers. They differ by what
+	// read-ahead holds, and by what an unread byte or a failed read left
+	// behind.
+	SourceBytes    int64 `json:"source_bytes"`
+	DeliveredBytes int64 `json:"delivered_bytes"`
+
+	// ThrottledFor is the time spent sleeping to stay under the limit, as
+	// opposed to waiting on the underlying reader.
+	ThrottledFor time.Duration `json:"throttled_for_ns"`
+
+	// Throughput1s, Throughput10s and Throughput60s are the bytes per second
+	// delivered over the last 1, 10 and 60 seconds, read like load averages
+	// to tell momentary dips from sustained slowdowns.
+	Throughput1s  int64 `json:"throughput_1s"`
+	Throughput10s int64 `json:"throughput_10s"`
+	Throughput60s int64 `json:"throughput_60s"`
+}
+
+const histogramBuckets = 32
+
+// Histogram counts sizes in power of two buckets: bucket i counts sizes in
+// [2^i, 2^(i+1)), with bucket 0 counting 0 too and the last bucket anything
+// larger.
+type Histogram [histogramBuckets]int64
+
+func (r *RateLimitedReader) Stats() Stats {
+	stats := Stats{
+		Limit:       r.limiter.Limit(),
+		BufferSizes: r.bufferSizes.snapshot(),
+		ReadSizes:   r.readSizes.snapshot(),
+
+		ReturnedSizes: r.returnedSizes.snapshot(),
+
+		SourceBytes:    r.sourceBytes.Load(),
+		DeliveredBytes: r.deliveredBytes.Load(),
+
+		ThrottledFor: time.Duration(r.throttledFor.Load()),
+	}
+
+	stats.Throughput1s, stats.Throughput10s, stats.Throughput60s = r.throughput.averages(time.Now())
+
+	if r.readAhead != nil {
+		stats.BufferedBytes, stats.BufferSize = r.readAhead.occupancy()
+	}
+	return stats
+}
+
+// sizeHistogram is a Histogram safe for concurrent use.
+type sizeHistogram [histogramBuckets]atomic.Int64
+
+func (h *sizeHistogram) add(size int) {
+	bucket := bits.Len64(uint64(size)) - 1
+	bucket = min(max(bucket, 0), histogramBuckets-1)
+	h[bucket].Add(1)
+}
+
+func (h *sizeHistogram) snapshot() Histogram {
+	var snapshot Histogram
+	for i := range h {
+		snapshot[i] = h[i].Load()
+	}
+	return snapshot
+}
+
+// mode returns the smallest size in the most counted bucket, 0 if empty.
+func (h *sizeHistogram) mode() int64 {
+	var mode int
+	var modeCount int64
+	for i := range h {
+		if count := h[i].Load(); count > modeCount {
+			mode, modeCount = i, count
+		}
+	}
+
+	if modeCount == 0 {
+		return 0
+	}
+	return 1 << mode
+}
@@ -0,0 +1,113 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Stats(t *testing.T) {
+	const limit = 1024
+
+	ratelimitedReader := NewRateLimitedReader(infiniteReader{}, limit)
+	stats := ratelimitedReader.Stats()
+	if stats.Limit != limit {
+		t.Fatalf("unexpected limit: %d expected: %d", stats.Limit, limit)
+	}
+	if stats.BufferSize != 0 || stats.BufferedBytes != 0 {
+		t.Fatalf("unexpected buffer stats without read-ahead: %+v", stats)
+	}
+}
+
+func TestRateLimitedReader_StatsHistograms(t *testing.T) {
+	const dataSize = 1024
+	const bufferSize = dataSize / 4
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), 0)
+	read(t, ratelimitedReader, bufferSize, dataSize)
+
+	stats := ratelimitedReader.Stats()
+	if stats.BufferSizes[8] != 4 { // 256 bytes fall in bucket 8
+		t.Fatalf("unexpected buffer sizes histogram: %v", stats.BufferSizes)
+	}
+	if stats.ReadSizes[8] != 4 {
+		t.Fatalf("unexpected read sizes histogram: %v", stats.ReadSizes)
+	}
+	if stats.ReturnedSizes[8] != 4 {
+		t.Fatalf("unexpected returned sizes histogram: %v", stats.ReturnedSizes)
+	}
+}
+
+func TestRateLimitedReader_StatsReturnedSizes(t *testing.T) {
+	const dataSize = 4 * 1024 // 4KB
+	const bufferSize = dataSize
+	limit := 1024 * 1000 / ReadIntervalMilliseconds // 1KB per read interval
+
+	// reading without waiting, throttling splits the 4KB asked for into
+	// smaller returns
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit)
+	buf := make([]byte, bufferSize)
+	for total := 0; total < dataSize; {
+		n, err := ratelimitedReader.TryRead(buf)
+		if errors.Is(err, ErrWouldBlock) {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		total += n
+	}
+
+	stats := ratelimitedReader.Stats()
+	var returns int64
+	for _, count := range stats.ReturnedSizes {
+		returns += count
+	}
+	if returns <= 1 || stats.ReturnedSizes[12] != 0 { // 4KB falls in bucket 12
+		t.Fatalf("unexpected returned sizes histogram: %v", stats.ReturnedSizes)
+	}
+}
+
+func TestRateLimitedReader_AutoChunk(t *testing.T) {
+	const dataSize = 20 * 1024  // 20KB
+	const bufferSize = dataSize // one read call
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithAutoChunk())
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	var reads int64
+	for _, count := range ratelimitedReader.Stats().ReadSizes {
+		reads += count
+	}
+	intervals := int64(1000 / ReadIntervalMilliseconds)
+	if reads > intervals/maxAutoChunkIntervals {
+		t.Fatalf("too many underlying reads: %d expected at most: %d", reads, intervals/maxAutoChunkIntervals)
+	}
+}
+
+func TestRateLimitedReader_StatsThrottledFor(t *testing.T) {
+	const dataSize = 10 * 1024
+	const bufferSize = dataSize / 10
+	const limit = dataSize // one second of throttling
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit)
+	if throttledFor := ratelimitedReader.Stats().ThrottledFor; throttledFor != 0 {
+		t.Fatalf("unexpected throttled time before reading: %v", throttledFor)
+	}
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	elapsed := time.Since(start)
+
+	throttledFor := ratelimitedReader.Stats().ThrottledFor
+	if throttledFor < 800*time.Millisecond || throttledFor > elapsed {
+		t.Fatalf("unexpected throttled time: %v elapsed: %v", throttledFor, elapsed)
+	}
+}
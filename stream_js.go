@@ -0,0 +1,136 @@
+package ratelimitedreader
+
+import (
+	"errors"
+	"io"
+	"syscall/js"
+)
+
+// NewReaderFromStream returns a reader pacing a JS ReadableStream, such as a
+// fetch response's body, for Go-in-the-browser apps. Closing it cancels the
+// stream.
+func NewReaderFromStream(stream js.Value, limit int64, opts ...Option) *RateLimitedReader {
+	return NewRateLimitedReadCloser(&jsStreamReader{reader: stream.Call("getReader")}, limit, opts...)
+}
+
+// ReadableStream returns a JS ReadableStream reading r at its limited rate in
+// chunks of up to chunkSize bytes, to hand to browser APIs such as Response
+// or a fetch upload's body. Canceling the stream closes r.
+func (r *RateLimitedReader) ReadableStream(chunkSize int) js.Value {
+	buf := make([]byte, max(chunkSize, 1))
+	var pull, cancel js.Func
+	// canceled is set once the stream was canceled, after which its
+	// controller mustn't be used
+	canceled := false
+	release := func() {
+		pull.Release()
+		cancel.Release()
+	}
+
+	pull = js.FuncOf(func(this js.Value, args []js.Value) any {
+		controller := args[0]
+		// Read blocks, which JS callbacks mustn't, so it resolves a promise
+		return newPromise(func(resolve, reject js.Value) {
+			n, err := r.Read(buf)
+			if canceled {
+				resolve.Invoke()
+				return
+			}
+			if n > 0 {
+				chunk := js.Global().Get("Uint8Array").New(n)
+				js.CopyBytesToJS(chunk, buf[:n])
+				controller.Call("enqueue", chunk)
+			}
+			switch {
+			case err == io.EOF:
+				controller.Call("close")
+				release()
+			case err != nil:
+				controller.Call("error", js.Global().Get("Error").New(err.Error()))
+				release()
+			}
+			resolve.Invoke()
+		})
+	})
+	cancel = js.FuncOf(func(this js.Value, args []js.Value) any {
+		canceled = true
+		r.Close()
+		release()
+		return nil
+	})
+
+	return js.Global().Get("ReadableStream").New(map[string]any{
+		"pull":   pull,
+		"cancel": cancel,
+	})
+}
+
+// jsStreamReader reads a JS ReadableStream through its default reader.
+type jsStreamReader struct {
+	reader js.Value
+	// chunk is what's left of the last chunk read
+	chunk []byte
+}
+
+func (s *jsStreamReader) Read(p []byte) (int, error) {
+	if len(s.chunk) == 0 {
+		result, err := await(s.reader.Call("read"))
+		if err != nil {
+			return 0, err
+		}
+		if result.Get("done").Bool() {
+			return 0, io.EOF
+		}
+
+		value := result.Get("value")
+		s.chunk = make([]byte, value.Get("length").Int())
+		js.CopyBytesToGo(s.chunk, value)
+	}
+
+	n := copy(p, s.chunk)
+	s.chunk = s.chunk[n:]
+	return n, nil
+}
+
+func (s *jsStreamReader) Close() error {
+	_, err := await(s.reader.Call("cancel"))
+	return err
+}
+
+// await blocks until promise settles, returning its value or its rejection
+// as an error.
+func await(promise js.Value) (js.Value, error) {
+	values := make(chan js.Value, 1)
+	errs := make(chan error, 1)
+	onResolve := js.FuncOf(func(this js.Value, args []js.Value) any {
+		values <- args[0]
+		return nil
+	})
+	defer onResolve.Release()
+	onReject := js.FuncOf(func(this js.Value, args []js.Value) any {
+		errs <- errors.New(js.Global().Get("String").Invoke(args[0]).String())
+		return nil
+	})
+	defer onReject.Release()
+
+	promise.Call("then", onResolve, onReject)
+	select {
+	case value := <-values:
+		return value, nil
+	case err := <-errs:
+		return js.Undefined(), err
+	}
+}
+
+// newPromise returns a JS promise settled by fn, which runs on its own
+// goroutine so it may block.
+func newPromise(fn func(resolve, reject js.Value)) js.Value {
+	var executor js.Func
+	executor = js.FuncOf(func(this js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go fn(resolve, reject)
+		executor.Release()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
@@ -0,0 +1,39 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_ReadableStream(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// paced into the stream, read back out of it unlimited
+	stream := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit).ReadableStream(bufferSize)
+	ratelimitedReader := NewReaderFromStream(stream, 0)
+	defer ratelimitedReader.Close()
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestNewReaderFromStream(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// the stream hands over large chunks, paced out in small reads
+	stream := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), 0).ReadableStream(dataSize)
+	ratelimitedReader := NewReaderFromStream(stream, limit)
+	defer ratelimitedReader.Close()
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
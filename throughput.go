@@ -0,0 +1,179 @@
+package ratelimitedreader
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	throughputWindow  = time.Second
+	throughputBuckets = 10
+
+	// notBindingFraction is how far below the limit throughput must stay for
+	// the limit to count as not binding
+	notBindingFraction = 2
+)
+
+// CurrentThroughput returns the bytes per second actually delivered to
+// callers over the last second, which falls below the limit when the source
+// or the consumer, rather than the limiter, is holding the transfer back.
+func (r *RateLimitedReader) CurrentThroughput() int64 {
+	return r.throughput.rate(time.Now())
+}
+
+// WithNotBindingCallback calls fn once throughput stays under half the limit
+// for after, signaling during triage that the source or the consumer, not
+// the limiter, is what holds the transfer back. fn is called from Read, once
+// per such stretch, with the throughput measured as CurrentThroughput and the
+// limit. after is at least a second, the window throughput is measured over.
+func WithNotBindingCallback(after time.Duration, fn func(throughput, limit int64)) Option {
+	return func(r *RateLimitedReader) {
+		r.notBinding = &notBinding{
+			stretch: rateStretch{after: max(after, throughputWindow)},
+			fn:      fn,
+		}
+	}
+}
+
+// notBinding calls fn once throughput stayed under the limit for long.
+type notBinding struct {
+	stretch rateStretch
+	fn      func(throughput, limit int64)
+}
+
+func (b *notBinding) observe(now time.Time, throughput, limit int64) {
+	below := limit > 0 && throughput*notBindingFraction < limit
+	if b.stretch.observe(now, below) {
+		b.fn(throughput, limit)
+	}
+}
+
+// rateStretch tracks how long throughput has stayed below some rate.
+type rateStretch struct {
+	after time.Duration
+
+	belowSince time.Time
+	fired      bool
+}
+
+// observe records whether throughput is below at now, reporting when it has
+// just stayed below for after.
+func (s *rateStretch) observe(now time.Time, below bool) bool {
+	if !below {
+		s.belowSince = time.Time{}
+		s.fired = false
+		return false
+	}
+
+	if s.belowSince.IsZero() {
+		s.belowSince = now
+	}
+	if !s.fired && now.Sub(s.belowSince) >= s.after {
+		s.fired = true
+		return true
+	}
+	return false
+}
+
+// throughputMeter counts bytes in rings of time buckets, finely over
+// throughputWindow and coarsely over a minute, so measuring costs no
+// allocations however many reads are made.
+type throughputMeter struct {
+	mu     sync.Mutex
+	recent rateRing
+	minute rateRing
+}
+
+const (
+	recentBucket  = throughputWindow / throughputBuckets
+	minuteBucket  = time.Second
+	minuteBuckets = 60
+)
+
+func (m *throughputMeter) add(now time.Time, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recent.add(now, recentBucket, throughputBuckets, n)
+	m.minute.add(now, minuteBucket, minuteBuckets, n)
+}
+
+// rate returns the bytes per second counted over throughputWindow ending at
+// now.
+func (m *throughputMeter) rate(now time.Time) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.recent.rate(now, recentBucket, throughputBuckets, throughputBuckets)
+}
+
+// averages returns the bytes per second counted over the last 1, 10 and 60
+// seconds ending at now.
+func (m *throughputMeter) averages(now time.Time) (avg1, avg10, avg60 int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	avg1 = m.recent.rate(now, recentBucket, throughputBuckets, throughputBuckets)
+	avg10 = m.minute.rate(now, minuteBucket, minuteBuckets, 10)
+	avg60 = m.minute.rate(now, minuteBucket, minuteBuckets, minuteBuckets)
+	return avg1, avg10, avg60
+}
+
+// windowRate returns the bytes per second counted over window ending at now,
+// measured over measuredWindow(window).
+func (m *throughputMeter) windowRate(now time.Time, window time.Duration) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	window = measuredWindow(window)
+	if window == throughputWindow {
+		return m.recent.rate(now, recentBucket, throughputBuckets, throughputBuckets)
+	}
+	return m.minute.rate(now, minuteBucket, minuteBuckets, int(window/minuteBucket))
+}
+
+// measuredWindow returns the window windowRate measures for window: at least
+// throughputWindow, rounded up to whole minuteBuckets and at most a minute.
+func measuredWindow(window time.Duration) time.Duration {
+	if window <= throughputWindow {
+		return throughputWindow
+	}
+	span := min((window+minuteBucket-1)/minuteBucket, minuteBuckets)
+	return span * minuteBucket
+}
+
+// rateRing is a ring of up to minuteBuckets counting buckets, each holding
+// the bytes of one bucket duration.
+type rateRing struct {
+	buckets [minuteBuckets]int64
+	// bucketIDs holds which bucket duration since the epoch each bucket
+	// counts, to tell stale buckets from current ones
+	bucketIDs [minuteBuckets]int64
+}
+
+func (g *rateRing) add(now time.Time, bucket time.Duration, size int, n int64) {
+	id := now.UnixNano() / int64(bucket)
+	slot := id % int64(size)
+	if g.bucketIDs[slot] != id {
+		g.bucketIDs[slot] = id
+		g.buckets[slot] = 0
+	}
+	g.buckets[slot] += n
+}
+
+// rate returns the bytes per second counted over the last span buckets
+// ending at now.
+func (g *rateRing) rate(now time.Time, bucket time.Duration, size, span int) int64 {
+	id := now.UnixNano() / int64(bucket)
+
+	var total int64
+	for slot := range size {
+		if g.bucketIDs[slot] > id-int64(span) && g.bucketIDs[slot] <= id {
+			total += g.buckets[slot]
+		}
+	}
+
+	// the current bucket is only partly over
+	window := int64(span-1)*int64(bucket) + now.UnixNano()%int64(bucket)
+	return mulDiv(total, int64(time.Second), window)
+}
@@ -0,0 +1,96 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_CurrentThroughput(t *testing.T) {
+	const limit = 20 * 1024
+	const sourceRate = limit / 4 // the source, not the limiter, is the constraint
+	const bufferSize = sourceRate / 20
+
+	ratelimitedReader := NewRateLimitedReader(slowReader{rate: sourceRate}, limit)
+	if throughput := ratelimitedReader.CurrentThroughput(); throughput != 0 {
+		t.Fatalf("unexpected throughput before reading: %d", throughput)
+	}
+
+	buf := make([]byte, bufferSize)
+	for start := time.Now(); time.Since(start) < 1200*time.Millisecond; {
+		ratelimitedReader.Read(buf)
+	}
+
+	throughput := ratelimitedReader.CurrentThroughput()
+	if throughput < sourceRate*3/4 || throughput > sourceRate*5/4 {
+		t.Fatalf("unexpected throughput: %d expected about: %d", throughput, sourceRate)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if throughput := ratelimitedReader.CurrentThroughput(); throughput != 0 {
+		t.Fatalf("unexpected throughput after idling: %d", throughput)
+	}
+}
+
+func TestRateLimitedReader_NotBindingCallback(t *testing.T) {
+	const limit = 20 * 1024
+	const sourceRate = limit / 4
+	const bufferSize = sourceRate / 20
+
+	var calls int
+	var lastThroughput int64
+	ratelimitedReader := NewRateLimitedReader(slowReader{rate: sourceRate}, limit,
+		WithNotBindingCallback(time.Second, func(throughput, l int64) {
+			calls++
+			lastThroughput = throughput
+			if l != limit {
+				t.Errorf("unexpected limit: %d expected: %d", l, limit)
+			}
+		}))
+
+	buf := make([]byte, bufferSize)
+	for start := time.Now(); time.Since(start) < 1500*time.Millisecond; {
+		ratelimitedReader.Read(buf)
+	}
+
+	if calls != 1 {
+		t.Fatalf("unexpected callback calls: %d expected: 1", calls)
+	}
+	if lastThroughput*notBindingFraction >= limit {
+		t.Fatalf("unexpected throughput reported: %d", lastThroughput)
+	}
+}
+
+func TestRateLimitedReader_NotBindingCallbackBinding(t *testing.T) {
+	const dataSize = 40 * 1024
+	const bufferSize = 1024
+	const limit = dataSize / 2
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit,
+		WithNotBindingCallback(time.Second, func(throughput, limit int64) {
+			t.Errorf("unexpected callback while the limit binds, throughput: %d limit: %d", throughput, limit)
+		}))
+	read(t, ratelimitedReader, bufferSize, dataSize)
+}
+
+func TestThroughputMeter_Averages(t *testing.T) {
+	const rate = 1000
+
+	var meter throughputMeter
+	start := time.Unix(1000, 0)
+	// a minute at rate, then a two second dip
+	for i := range 58 * 10 {
+		meter.add(start.Add(time.Duration(i)*100*time.Millisecond), rate/10)
+	}
+
+	avg1, avg10, avg60 := meter.averages(start.Add(60 * time.Second))
+	if avg1 != 0 {
+		t.Fatalf("unexpected 1s average during the dip: %d", avg1)
+	}
+	if avg10 < rate*7/10 || avg10 > rate*9/10 {
+		t.Fatalf("unexpected 10s average: %d expected about: %d", avg10, rate*8/10)
+	}
+	if avg60 < rate*9/10 || avg60 > rate {
+		t.Fatalf("unexpected 60s average: %d expected about: %d", avg60, rate*58/60)
+	}
+}
@@ -0,0 +1,21 @@
+package ratelimitedreader
+
+// WithHighResolutionTimer asks the OS for 1ms timer resolution while the
+// reader is open, for accurate pacing where timers are coarse: Windows'
+// default 15.6ms granularity skews 50ms intervals by up to a third. It's a
+// no-op elsewhere. The resolution is system wide on Windows and held until
+// Close, so close readers given it.
+func WithHighResolutionTimer() Option {
+	return func(r *RateLimitedReader) {
+		if beginHighResTimer() {
+			r.highResTimer.Store(true)
+		}
+	}
+}
+
+// releaseHighResTimer releases r's timer resolution, once.
+func (r *RateLimitedReader) releaseHighResTimer() {
+	if r.highResTimer.Swap(false) {
+		endHighResTimer()
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package ratelimitedreader
+
+// beginHighResTimer is a no-op outside Windows, whose timers are fine
+// grained already.
+func beginHighResTimer() bool {
+	return false
+}
+
+func endHighResTimer() {}
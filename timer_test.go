@@ -0,0 +1,41 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_HighResolutionTimerPrecision(t *testing.T) {
+	const sleeps = 20
+	const sleepTime = time.Millisecond
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(nil), 0, WithHighResolutionTimer())
+	defer ratelimitedReader.Close()
+
+	start := time.Now()
+	for range sleeps {
+		time.Sleep(sleepTime)
+	}
+	// coarse 15.6ms timers would make this about 16ms
+	if mean := time.Since(start) / sleeps; mean > 4*sleepTime {
+		t.Fatalf("timer too coarse, mean sleep: %v for: %v", mean, sleepTime)
+	}
+}
+
+func TestRateLimitedReader_HighResolutionTimerPacing(t *testing.T) {
+	const dataSize = 20 * 1024        // 20KB
+	const bufferSize = dataSize / 100 // many short sleeps
+	const partsAmount = 2
+	const limit = dataSize / partsAmount
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithHighResolutionTimer())
+	defer ratelimitedReader.Close()
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	elapsed := time.Since(start)
+	if drift := (elapsed - partsAmount*time.Second).Abs(); drift > 50*time.Millisecond {
+		t.Fatalf("unexpected pacing drift: %v elapsed: %v", drift, elapsed)
+	}
+}
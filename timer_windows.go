@@ -0,0 +1,24 @@
+package ratelimitedreader
+
+import "syscall"
+
+var (
+	winmm           = syscall.NewLazyDLL("winmm.dll")
+	timeBeginPeriod = winmm.NewProc("timeBeginPeriod")
+	timeEndPeriod   = winmm.NewProc("timeEndPeriod")
+)
+
+// beginHighResTimer asks Windows for 1ms timer resolution, in place of its
+// default 15.6ms, reporting whether it was granted.
+func beginHighResTimer() bool {
+	if timeBeginPeriod.Find() != nil {
+		return false
+	}
+	ret, _, _ := timeBeginPeriod.Call(1)
+	return ret == 0 // TIMERR_NOERROR
+}
+
+// endHighResTimer releases a resolution granted by beginHighResTimer.
+func endHighResTimer() {
+	timeEndPeriod.Call(1)
+}
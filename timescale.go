@@ -0,0 +1,32 @@
+package ratelimitedreader
+
+import (
+	"fmt"
+	"math"
+)
+
+// WithTimeScale scales the delays pacing the reader by f, e.g. 0.1 to run 10
+// times faster, so staging environments can run production limits without
+// production wall-clock durations. The limit, its updates and Stats keep
+// reading as configured; the reader merely reads f times the time. Limiters
+// shared with other readers are drawn from at the scaled rate, and
+// algorithms set with WithAlgorithm pace on their own. A factor that isn't
+// positive and finite is ignored, and makes NewReaderE fail with
+// ErrInvalidLimit.
+func WithTimeScale(f float64) Option {
+	return func(r *RateLimitedReader) {
+		if f <= 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			r.optErr = fmt.Errorf("%w: time scale %v", ErrInvalidLimit, f)
+			return
+		}
+		r.timeScale = f
+	}
+}
+
+// scaled returns limit, a budget per interval, scaled by r's time scale.
+func (r *RateLimitedReader) scaled(limit int64) int64 {
+	if r.timeScale <= 0 || limit <= 0 {
+		return limit
+	}
+	return max(int64(float64(limit)/r.timeScale), 1)
+}
@@ -0,0 +1,45 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_TimeScale(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 4
+	const limit = dataSize / 10 // 10 seconds unscaled
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithTimeScale(0.1))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 1, 1)
+
+	if got := ratelimitedReader.Stats().Limit; got != limit {
+		t.Fatalf("unexpected limit: %d expected: %d", got, limit)
+	}
+}
+
+func TestRateLimitedReader_TimeScaleSlower(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = dataSize / 4
+	const limit = dataSize
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithTimeScale(2))
+
+	start := time.Now()
+	read(t, ratelimitedReader, bufferSize, dataSize)
+	assertReadTimes(t, time.Since(start), 2, 2)
+}
+
+func TestRateLimitedReader_InvalidTimeScale(t *testing.T) {
+	for _, f := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		if _, err := NewReaderE(bytes.NewReader(nil), 1024, WithTimeScale(f)); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("unexpected error for time scale %v: %v expected: %v", f, err, ErrInvalidLimit)
+		}
+	}
+}
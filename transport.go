@@ -0,0 +1,324 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottledTransport is an http.RoundTripper that rate limits response
+// bodies. Hosts can be given their own limit, shared by all of that host's
+// responses, so a client stays polite to specific origins while other hosts
+// run at the default limit, which applies to each response on its own.
+// Requests made with WithRequestLimit use their own limit instead, and ones
+// whose context carries a Limiter from NewContext draw from it.
+type ThrottledTransport struct {
+	base  http.RoundTripper
+	limit int64
+
+	mu           sync.RWMutex
+	hostLimits   map[string]int64
+	hostLimitFn  func(host string) (limit int64, ok bool)
+	hostLimiters map[string]*Limiter
+
+	responseLimitFn func(resp *http.Response) (limit int64, ok bool)
+
+	adaptive bool
+	backoffs map[string]*hostBackoff
+}
+
+// hostBackoff is what a host told an adaptive transport to slow down to.
+type hostBackoff struct {
+	// limit caps the host's limit, 0 when not capped
+	limit   int64
+	retryAt time.Time
+}
+
+// NewThrottledTransport returns a transport sending requests through base,
+// or http.DefaultTransport if nil, with limit as the default per-response
+// limit.
+func NewThrottledTransport(base http.RoundTripper, limit int64) *ThrottledTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &ThrottledTransport{
+		base:         base,
+		limit:        limit,
+		hostLimits:   make(map[string]int64),
+		hostLimiters: make(map[string]*Limiter),
+		backoffs:     make(map[string]*hostBackoff),
+	}
+}
+
+// SetHostLimit limits all responses from host, matched without the port, to
+// limit together. It takes effect on in-flight responses too.
+func (t *ThrottledTransport) SetHostLimit(host string, limit int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hostLimits[host] = limit
+	if limiter, ok := t.hostLimiters[host]; ok {
+		limiter.SetLimit(t.effectiveHostLimit(host, limit))
+	}
+}
+
+// RemoveHostLimit puts host back on the default limit for new requests.
+func (t *ThrottledTransport) RemoveHostLimit(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.hostLimits, host)
+	delete(t.hostLimiters, host)
+	delete(t.backoffs, host)
+}
+
+// SetHostLimitFunc sets a hook consulted on every request before the host
+// limits set with SetHostLimit. Hosts it reports no limit for fall through.
+func (t *ThrottledTransport) SetHostLimitFunc(fn func(host string) (limit int64, ok bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hostLimitFn = fn
+}
+
+// SetResponseLimitFunc sets a hook that picks a limit for a response from the
+// response itself, so servers can steer how fast clients pull from them. It
+// can only lower the host or default limit the response would get otherwise,
+// never lift it, and doesn't apply to requests with a limit or limiter of
+// their own. Limits of 0 or below are ignored. See LimitFromHeader.
+func (t *ThrottledTransport) SetResponseLimitFunc(fn func(resp *http.Response) (limit int64, ok bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.responseLimitFn = fn
+}
+
+// LimitFromHeader returns a response limit hook reading the limit in bytes
+// per second from header, e.g. X-Bandwidth-Limit. Values of 0 or below are
+// rejected, so a server can't turn throttling off.
+func LimitFromHeader(header string) func(resp *http.Response) (limit int64, ok bool) {
+	return func(resp *http.Response) (int64, bool) {
+		limit, err := strconv.ParseInt(resp.Header.Get(header), 10, 64)
+		return limit, err == nil && limit > 0
+	}
+}
+
+// SetAdaptive makes the transport back off from hosts answering 429 Too Many
+// Requests: it holds further requests to the host until Retry-After, or
+// X-RateLimit-Reset once X-RateLimit-Remaining hits 0, and halves the host's
+// limit on every 429, then recovers a tenth of it per successful response.
+// Hosts with no limit at all are only held, as there is nothing to halve.
+func (t *ThrottledTransport) SetAdaptive(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.adaptive = enabled
+	if !enabled {
+		t.backoffs = make(map[string]*hostBackoff)
+	}
+}
+
+func (t *ThrottledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if err := t.waitRetry(req, host); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.observe(host, resp)
+	if resp.Body == nil {
+		return resp, nil
+	}
+
+	if limit, ok := requestLimit(req.Context()); ok {
+		resp.Body = NewRateLimitedReadCloser(resp.Body, limit)
+	} else if limiter, ok := FromContext(req.Context()); ok {
+		resp.Body = newRateLimitedReadCloser(resp.Body, limiter)
+	} else if limiter := t.hostLimiter(host); limiter != nil {
+		var opts []Option
+		if limit, ok := t.responseLimit(resp); ok {
+			// a tier on the shared host limiter can only lower the rate
+			opts = append(opts, WithTiers([]Tier{{Limit: limit}}))
+		}
+		resp.Body = newRateLimitedReadCloser(resp.Body, limiter, opts...)
+	} else {
+		limit := t.limit
+		if responseLimit, ok := t.responseLimit(resp); ok && (limit <= 0 || responseLimit < limit) {
+			limit = responseLimit
+		}
+		resp.Body = NewRateLimitedReadCloser(resp.Body, limit)
+	}
+	return resp, nil
+}
+
+func (t *ThrottledTransport) responseLimit(resp *http.Response) (int64, bool) {
+	t.mu.RLock()
+	fn := t.responseLimitFn
+	t.mu.RUnlock()
+
+	if fn == nil {
+		return 0, false
+	}
+	limit, ok := fn(resp)
+	return limit, ok && limit > 0
+}
+
+// hostLimiter returns the limiter shared by host's responses, or nil if host
+// has no limit of its own.
+func (t *ThrottledTransport) hostLimiter(host string) *Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limit, ok := int64(0), false
+	if t.hostLimitFn != nil {
+		limit, ok = t.hostLimitFn(host)
+	}
+	if !ok {
+		limit, ok = t.hostLimits[host]
+	}
+	if backoff := t.backoffs[host]; !ok && backoff != nil && backoff.limit > 0 {
+		// backed off hosts share a limit even without one of their own
+		limit, ok = t.limit, true
+	}
+	if !ok {
+		return nil
+	}
+
+	limiter, ok := t.hostLimiters[host]
+	if !ok {
+		limiter = NewLimiter(limit)
+		t.hostLimiters[host] = limiter
+	}
+	limiter.SetLimit(t.effectiveHostLimit(host, limit))
+	return limiter
+}
+
+// effectiveHostLimit returns limit capped by host's backoff. Must hold t.mu.
+func (t *ThrottledTransport) effectiveHostLimit(host string, limit int64) int64 {
+	backoff := t.backoffs[host]
+	if backoff == nil || backoff.limit <= 0 {
+		return limit
+	}
+
+	if limit <= 0 || backoff.limit < limit {
+		return backoff.limit
+	}
+	return limit
+}
+
+// waitRetry holds req until host's retry time has passed.
+func (t *ThrottledTransport) waitRetry(req *http.Request, host string) error {
+	t.mu.RLock()
+	var retryAt time.Time
+	if backoff := t.backoffs[host]; backoff != nil {
+		retryAt = backoff.retryAt
+	}
+	t.mu.RUnlock()
+
+	wait := time.Until(retryAt)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// observe adapts host's backoff to resp when the transport is adaptive.
+func (t *ThrottledTransport) observe(host string, resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.adaptive {
+		return
+	}
+
+	configured, ok := t.hostLimits[host]
+	if t.hostLimitFn != nil {
+		if limit, fnOk := t.hostLimitFn(host); fnOk {
+			configured, ok = limit, true
+		}
+	}
+	if !ok {
+		configured = t.limit
+	}
+
+	backoff := t.backoffs[host]
+	if backoff == nil {
+		backoff = &hostBackoff{}
+		t.backoffs[host] = backoff
+	}
+
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		current := t.effectiveHostLimit(host, configured)
+		if current > 0 {
+			backoff.limit = max(current/2, 1)
+		}
+		if retryAt, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			backoff.retryAt = retryAt
+		}
+	} else if backoff.limit > 0 && configured > 0 {
+		backoff.limit += max(configured/10, 1)
+		if backoff.limit >= configured {
+			backoff.limit = 0
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if resetAt, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok && resetAt.After(backoff.retryAt) {
+			backoff.retryAt = resetAt
+		}
+	}
+
+	if limiter, ok := t.hostLimiters[host]; ok {
+		limiter.SetLimit(t.effectiveHostLimit(host, configured))
+	}
+	if backoff.limit <= 0 && !backoff.retryAt.After(now) {
+		delete(t.backoffs, host)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	retryAt, err := http.ParseTime(value)
+	return retryAt, err == nil
+}
+
+// parseRateLimitReset parses an X-RateLimit-Reset header, which servers send
+// either as seconds until the reset or as a unix timestamp.
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// a delta would be years long at this size
+	if seconds > 1e9 {
+		return time.Unix(seconds, 0), true
+	}
+	return now.Add(time.Duration(seconds) * time.Second), true
+}
@@ -0,0 +1,352 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottledTransport_DefaultLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := serveData(dataSize)
+	defer server.Close()
+	client := &http.Client{Transport: NewThrottledTransport(nil, limit)}
+
+	start := time.Now()
+	get(t, client, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestThrottledTransport_HostLimitShared(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB per response
+	const responsesAmount = 2
+	const limit = dataSize // a second per response
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetHostLimit("127.0.0.1", limit)
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < responsesAmount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, client, server.URL, dataSize)
+		}()
+	}
+	wg.Wait()
+	// concurrent responses from one host share its limit
+	assertReadTimes(t, time.Since(start), responsesAmount, responsesAmount+1)
+}
+
+func TestThrottledTransport_HostLimitFunc(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const limit = 1024          // slow default
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, limit)
+	transport.SetHostLimit("127.0.0.1", limit)
+	transport.SetHostLimitFunc(func(host string) (int64, bool) {
+		return 0, host == "127.0.0.1" // full speed
+	})
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	get(t, client, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestThrottledTransport_RemoveHostLimit(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetHostLimit("127.0.0.1", 1024)
+	transport.RemoveHostLimit("127.0.0.1")
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	get(t, client, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestThrottledTransport_RequestLimit(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetHostLimit("127.0.0.1", 0)
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequestWithContext(WithRequestLimit(context.Background(), limit), http.MethodGet, server.URL, nil)
+	start := time.Now()
+	do(t, client, req, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestThrottledTransport_RequestLimitBypass(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+	const limit = 1024          // slow default
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, limit)
+	transport.SetHostLimit("127.0.0.1", limit)
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequestWithContext(WithRequestLimit(context.Background(), 0), http.MethodGet, server.URL, nil)
+	start := time.Now()
+	do(t, client, req, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestThrottledTransport_AdaptiveRetryAfter(t *testing.T) {
+	const retryAfterSeconds = 1
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetAdaptive(true)
+	client := &http.Client{Transport: transport}
+
+	get(t, client, server.URL, 0)
+
+	start := time.Now()
+	get(t, client, server.URL, 0)
+	assertReadTimes(t, time.Since(start), retryAfterSeconds, retryAfterSeconds)
+}
+
+func TestThrottledTransport_AdaptiveRateLimitReset(t *testing.T) {
+	const resetSeconds = 1
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(resetSeconds))
+		}
+	}))
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetAdaptive(true)
+	client := &http.Client{Transport: transport}
+
+	get(t, client, server.URL, 0)
+
+	start := time.Now()
+	get(t, client, server.URL, 0)
+	assertReadTimes(t, time.Since(start), resetSeconds, resetSeconds)
+}
+
+func TestThrottledTransport_AdaptiveLimit(t *testing.T) {
+	const limit = 100 * 1024
+
+	var tooMany atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tooMany.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetHostLimit("127.0.0.1", limit)
+	transport.SetAdaptive(true)
+	client := &http.Client{Transport: transport}
+
+	tooMany.Store(true)
+	get(t, client, server.URL, 0)
+	get(t, client, server.URL, 0)
+	if current := transport.hostLimiter("127.0.0.1").Limit(); current != limit/4 {
+		t.Fatalf("unexpected limit after two 429s: %d expected: %d", current, limit/4)
+	}
+
+	tooMany.Store(false)
+	get(t, client, server.URL, 0)
+	if current := transport.hostLimiter("127.0.0.1").Limit(); current != limit/4+limit/10 {
+		t.Fatalf("unexpected limit after recovering once: %d expected: %d", current, limit/4+limit/10)
+	}
+
+	for i := 0; i < 10; i++ {
+		get(t, client, server.URL, 0)
+	}
+	if current := transport.hostLimiter("127.0.0.1").Limit(); current != limit {
+		t.Fatalf("unexpected limit after recovering: %d expected: %d", current, limit)
+	}
+}
+
+func TestThrottledTransport_NotAdaptive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewThrottledTransport(nil, 0)}
+
+	start := time.Now()
+	get(t, client, server.URL, 0)
+	get(t, client, server.URL, 0)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestParseRateLimitReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	resetAt, ok := parseRateLimitReset("30", now)
+	if !ok || !resetAt.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("unexpected reset for delta seconds: %v", resetAt)
+	}
+
+	resetAt, ok = parseRateLimitReset("1700000060", now)
+	if !ok || !resetAt.Equal(time.Unix(1700000060, 0)) {
+		t.Fatalf("unexpected reset for unix timestamp: %v", resetAt)
+	}
+
+	if _, ok = parseRateLimitReset("soon", now); ok {
+		t.Fatalf("expected invalid reset to fail parsing")
+	}
+}
+
+func TestThrottledTransport_LimitFromHeader(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Bandwidth-Limit", strconv.Itoa(limit))
+		w.Write(make([]byte, dataSize))
+	}))
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetResponseLimitFunc(LimitFromHeader("X-Bandwidth-Limit"))
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	get(t, client, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestThrottledTransport_LimitFromMissingHeader(t *testing.T) {
+	const dataSize = 100 * 1024 // 100KB
+
+	server := serveData(dataSize)
+	defer server.Close()
+	transport := NewThrottledTransport(nil, 0)
+	transport.SetResponseLimitFunc(LimitFromHeader("X-Bandwidth-Limit"))
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	get(t, client, server.URL, dataSize)
+	assertReadTimes(t, time.Since(start), 0, 0)
+}
+
+func TestThrottledTransport_LimitFromHeaderOnlyLowers(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	tests := []struct {
+		name      string
+		header    string
+		hostLimit int64
+		seconds   int
+	}{
+		{"zero", "0", 0, partsAmount},
+		{"negative", "-1", 0, partsAmount},
+		{"above default", strconv.Itoa(limit * 4), 0, partsAmount},
+		{"below default", strconv.Itoa(limit / 2), 0, partsAmount * 2},
+		{"zero on host", "0", limit, partsAmount},
+		{"above host", strconv.Itoa(limit * 4), limit, partsAmount},
+		{"below host", strconv.Itoa(limit / 2), limit, partsAmount * 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Bandwidth-Limit", tt.header)
+				w.Write(make([]byte, dataSize))
+			}))
+			defer server.Close()
+
+			// the host limit applies in place of the default, left unlimited
+			transport := NewThrottledTransport(nil, limit)
+			if tt.hostLimit > 0 {
+				transport = NewThrottledTransport(nil, 0)
+				transport.SetHostLimit("127.0.0.1", tt.hostLimit)
+			}
+			transport.SetResponseLimitFunc(LimitFromHeader("X-Bandwidth-Limit"))
+			client := &http.Client{Transport: transport}
+
+			start := time.Now()
+			get(t, client, server.URL, dataSize)
+			assertReadTimes(t, time.Since(start), tt.seconds, tt.seconds)
+		})
+	}
+}
+
+func serveData(dataSize int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, dataSize))
+	}))
+}
+
+func get(t *testing.T, client *http.Client, url string, expectedDataSize int) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	do(t, client, req, expectedDataSize)
+}
+
+func do(t *testing.T, client *http.Client, req *http.Request, expectedDataSize int) {
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(data) != expectedDataSize {
+		t.Errorf("read incomplete data, read: %d expected: %d", len(data), expectedDataSize)
+	}
+}
+
+func TestThrottledTransport_ContextLimiter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	server := serveData(dataSize)
+	defer server.Close()
+	client := &http.Client{Transport: NewThrottledTransport(nil, 0)}
+
+	ctx := NewContext(context.Background(), NewLimiter(limit))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	do(t, client, req, dataSize)
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
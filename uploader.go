@@ -0,0 +1,123 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Uploader runs queued uploads on concurrency workers, each writing through a
+// RateLimitedWriter drawing from limiter, so together they never exceed its
+// limit. Uploads start in the order they were queued.
+type Uploader struct {
+	limiter *Limiter
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*UploadJob
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// UploadJob is an upload queued on an Uploader, reporting its progress.
+type UploadJob struct {
+	dst io.Writer
+	src io.Reader
+
+	written atomic.Int64
+	done    chan struct{}
+	err     error
+}
+
+func NewUploader(concurrency int, limiter *Limiter) *Uploader {
+	u := &Uploader{limiter: limiter}
+	u.cond = sync.NewCond(&u.mu)
+	for range max(concurrency, 1) {
+		u.wg.Add(1)
+		go u.work()
+	}
+	return u
+}
+
+// Upload queues copying src to dst until EOF. On a closed uploader the job
+// fails at once with ErrClosed.
+func (u *Uploader) Upload(dst io.Writer, src io.Reader) *UploadJob {
+	job := &UploadJob{dst: dst, src: src, done: make(chan struct{})}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		job.err = ErrClosed
+		close(job.done)
+		return job
+	}
+	u.queue = append(u.queue, job)
+	u.cond.Signal()
+	return job
+}
+
+// Close stops taking uploads and waits for the queued ones to finish.
+func (u *Uploader) Close() error {
+	u.mu.Lock()
+	u.closed = true
+	u.cond.Broadcast()
+	u.mu.Unlock()
+
+	u.wg.Wait()
+	return nil
+}
+
+// work runs queued jobs until the uploader is closed and the queue drained.
+func (u *Uploader) work() {
+	defer u.wg.Done()
+
+	buf := make([]byte, defaultCopyBufferSize)
+	for {
+		u.mu.Lock()
+		for len(u.queue) == 0 && !u.closed {
+			u.cond.Wait()
+		}
+		if len(u.queue) == 0 {
+			u.mu.Unlock()
+			return
+		}
+		job := u.queue[0]
+		u.queue[0] = nil
+		u.queue = u.queue[1:]
+		u.mu.Unlock()
+
+		dst := NewWriterWithLimiter(jobWriter{job}, u.limiter)
+		_, job.err = copyBuffer(dst, job.src, buf)
+		close(job.done)
+	}
+}
+
+// Written returns how many bytes of the job were written so far.
+func (j *UploadJob) Written() int64 {
+	return j.written.Load()
+}
+
+// Done is closed once the job finished.
+func (j *UploadJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to finish and returns its error.
+func (j *UploadJob) Wait() error {
+	<-j.done
+	return j.err
+}
+
+// jobWriter writes to a job's destination, counting its progress.
+type jobWriter struct {
+	job *UploadJob
+}
+
+func (w jobWriter) Write(p []byte) (int, error) {
+	n, err := w.job.dst.Write(p)
+	w.job.written.Add(int64(n))
+	return n, err
+}
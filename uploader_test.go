@@ -0,0 +1,51 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestUploader(t *testing.T) {
+	const dataSize = 10 * 1024 // 10KB per job
+	const jobsAmount = 4
+	const concurrency = 2
+	const partsAmount = 2
+	const limit = dataSize * jobsAmount / partsAmount
+
+	uploader := NewUploader(concurrency, NewLimiter(limit))
+
+	start := time.Now()
+	jobs := make([]*UploadJob, jobsAmount)
+	dsts := make([]*bytes.Buffer, jobsAmount)
+	for i := range jobs {
+		dsts[i] = &bytes.Buffer{}
+		jobs[i] = uploader.Upload(dsts[i], bytes.NewReader(make([]byte, dataSize)))
+	}
+
+	// progress shows while the first jobs run
+	time.Sleep(500 * time.Millisecond)
+	if written := jobs[0].Written(); written == 0 || written == dataSize {
+		t.Fatalf("unexpected progress of a running job: %d", written)
+	}
+	if written := jobs[jobsAmount-1].Written(); written != 0 {
+		t.Fatalf("unexpected progress of a queued job: %d", written)
+	}
+
+	for i, job := range jobs {
+		if err := job.Wait(); err != nil {
+			t.Fatalf("unexpected error for job %d: %v", i, err)
+		}
+		if job.Written() != dataSize || dsts[i].Len() != dataSize {
+			t.Fatalf("unexpected written for job %d: %d expected: %d", i, job.Written(), dataSize)
+		}
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount)
+
+	uploader.Close()
+	if err := uploader.Upload(&bytes.Buffer{}, bytes.NewReader(nil)).Wait(); err != ErrClosed {
+		t.Fatalf("unexpected error for an upload after close: %v expected: %v", err, ErrClosed)
+	}
+}
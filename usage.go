@@ -0,0 +1,99 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"sync"
+	"time"
+)
+
+// UsageReporter batches the bytes readers deliver per label, such as a
+// tenant or a direction, and flushes them to a callback every interval, for
+// metering and billing. Give it to readers with WithUsageReporter.
+type UsageReporter struct {
+	fn func(usage map[string]int64)
+
+	mu     sync.Mutex
+	usage  map[string]int64
+	timer  *time.Timer
+	closed bool
+
+	// flushMu orders flushes, so fn sees batches in order
+	flushMu sync.Mutex
+}
+
+// NewUsageReporter returns a reporter calling fn every interval with the
+// bytes delivered per label since the last call, skipping intervals without
+// any. fn runs on the reporter's own goroutine and owns the map it's given.
+func NewUsageReporter(interval time.Duration, fn func(usage map[string]int64)) *UsageReporter {
+	u := &UsageReporter{
+		fn:    fn,
+		usage: make(map[string]int64),
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.timer = time.AfterFunc(interval, func() {
+		u.Flush()
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if !u.closed {
+			u.timer.Reset(interval)
+		}
+	})
+	return u
+}
+
+// WithUsageReporter reports the bytes the reader delivers to u under label.
+func WithUsageReporter(u *UsageReporter, label string) Option {
+	return func(r *RateLimitedReader) {
+		r.usage = &usageTap{reporter: u, label: label}
+	}
+}
+
+// Add counts n bytes under label, for usage not read through a reader, such
+// as writes.
+func (u *UsageReporter) Add(label string, n int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.usage[label] += n
+}
+
+// Flush calls fn with the usage batched so far, if any, without waiting for
+// the interval.
+func (u *UsageReporter) Flush() {
+	u.flushMu.Lock()
+	defer u.flushMu.Unlock()
+
+	u.mu.Lock()
+	usage := u.usage
+	u.usage = make(map[string]int64)
+	u.mu.Unlock()
+
+	if len(usage) > 0 {
+		u.fn(usage)
+	}
+}
+
+// Close stops the periodic flushes and flushes what's left. Usage added
+// after Close is flushed only by calling Flush.
+func (u *UsageReporter) Close() {
+	u.mu.Lock()
+	u.closed = true
+	u.timer.Stop()
+	u.mu.Unlock()
+
+	u.Flush()
+}
+
+// usageTap reports a reader's deliveries under its label.
+type usageTap struct {
+	reporter *UsageReporter
+	label    string
+}
+
+func (t *usageTap) add(n int64) {
+	t.reporter.Add(t.label, n)
+}
@@ -0,0 +1,45 @@
+//go:build !tinygo && !ratelimitedreader_minimal
+
+package ratelimitedreader
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUsageReporter(t *testing.T) {
+	const dataSize = 10 * 1024
+	const bufferSize = 1024
+	const limit = dataSize * 10
+
+	var mu sync.Mutex
+	var flushes int
+	total := make(map[string]int64)
+	reporter := NewUsageReporter(50*time.Millisecond, func(usage map[string]int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		flushes++
+		for label, n := range usage {
+			total[label] += n
+		}
+	})
+
+	tenantA := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit, WithUsageReporter(reporter, "a"))
+	tenantB := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize*2)), limit, WithUsageReporter(reporter, "b"))
+	read(t, tenantA, bufferSize, dataSize)
+	read(t, tenantB, bufferSize, dataSize*2)
+	reporter.Add("b", dataSize)
+	reporter.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total["a"] != dataSize || total["b"] != dataSize*3 {
+		t.Fatalf("unexpected usage: %v expected a: %d b: %d", total, dataSize, dataSize*3)
+	}
+	// reads took a few intervals, which flushed in batches
+	if flushes < 2 {
+		t.Fatalf("unexpected flushes: %d expected at least 2", flushes)
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimitedreader
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Verifier passes reads through unchanged while checking that no rolling one
+// second window saw more than limit bytes, so tests can assert a pipeline
+// kept to a limit on real traffic. Bytes count at the time Read returns them.
+type Verifier struct {
+	reader io.Reader
+	limit  int64
+
+	mu       sync.Mutex
+	reads    []verifiedRead
+	inWindow int64
+	peak     int64
+}
+
+// verifiedRead is one Read within the current window.
+type verifiedRead struct {
+	at time.Time
+	n  int64
+}
+
+func NewVerifier(reader io.Reader, limit int64) *Verifier {
+	return &Verifier{
+		reader: reader,
+		limit:  limit,
+	}
+}
+
+func (v *Verifier) Read(p []byte) (n int, err error) {
+	n, err = v.reader.Read(p)
+	if n > 0 {
+		v.record(time.Now(), int64(n))
+	}
+	return n, err
+}
+
+func (v *Verifier) record(now time.Time, n int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.reads = append(v.reads, verifiedRead{at: now, n: n})
+	v.inWindow += n
+
+	windowStart := now.Add(-time.Second)
+	evicted := 0
+	for evicted < len(v.reads) && !v.reads[evicted].at.After(windowStart) {
+		v.inWindow -= v.reads[evicted].n
+		evicted++
+	}
+	v.reads = v.reads[evicted:]
+
+	v.peak = max(v.peak, v.inWindow)
+}
+
+// Peak returns the most bytes read within any one second window so far.
+func (v *Verifier) Peak() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.peak
+}
+
+// Exceeded reports whether any one second window went over the limit.
+func (v *Verifier) Exceeded() bool {
+	return v.Excess() > 0
+}
+
+// Excess returns by how many bytes the worst window went over the limit, 0 if
+// none did.
+func (v *Verifier) Excess() int64 {
+	return max(v.Peak()-v.limit, 0)
+}
@@ -0,0 +1,43 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestVerifier_WithinLimit(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const limit = dataSize / 2 // two seconds
+
+	iterLimit := int64(limit / (1000 / ReadIntervalMilliseconds))
+
+	ratelimitedReader := NewRateLimitedReader(bytes.NewReader(make([]byte, dataSize)), limit)
+	verifier := NewVerifier(ratelimitedReader, limit)
+	// reads of an interval's budget each, as bytes only count once returned
+	if _, err := io.CopyBuffer(struct{ io.Writer }{io.Discard}, struct{ io.Reader }{verifier}, make([]byte, iterLimit)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// reads land on interval boundaries, so a window can catch one extra
+	if verifier.Excess() > iterLimit {
+		t.Fatalf("limit exceeded by %d bytes, peak: %d limit: %d", verifier.Excess(), verifier.Peak(), limit)
+	}
+}
+
+func TestVerifier_Exceeded(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const limit = dataSize / 2
+
+	verifier := NewVerifier(bytes.NewReader(make([]byte, dataSize)), limit)
+	if _, err := io.Copy(io.Discard, verifier); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !verifier.Exceeded() {
+		t.Fatalf("expected an unlimited read to exceed the limit")
+	}
+	if verifier.Peak() != dataSize || verifier.Excess() != dataSize-limit {
+		t.Fatalf("unexpected peak: %d excess: %d expected: %d %d", verifier.Peak(), verifier.Excess(), dataSize, dataSize-limit)
+	}
+}
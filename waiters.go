@@ -0,0 +1,64 @@
+package ratelimitedreader
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Waiter is a caller blocked on a Limiter, waiting for its turn.
+type Waiter struct {
+	// Bytes is how much the caller is waiting to read or write, and Since
+	// when it started waiting.
+	Bytes int64
+	Since time.Time
+}
+
+// waiters tracks the callers blocked on a limiter.
+type waiters struct {
+	mu      sync.Mutex
+	waiting map[*Waiter]struct{}
+}
+
+// Waiters returns the callers currently blocked waiting for l's budget,
+// longest waiting first, so operators can see a queue building up on a
+// shared limiter before users notice. Callers only waiting on a priority
+// turn, see WithPriority, aren't counted until their own sleep starts.
+func (l *Limiter) Waiters() []Waiter {
+	l.waiters.mu.Lock()
+	defer l.waiters.mu.Unlock()
+
+	snapshot := make([]Waiter, 0, len(l.waiters.waiting))
+	for waiter := range l.waiters.waiting {
+		snapshot = append(snapshot, *waiter)
+	}
+	slices.SortFunc(snapshot, func(a, b Waiter) int {
+		return a.Since.Compare(b.Since)
+	})
+	return snapshot
+}
+
+// enqueue counts a caller as waiting for n bytes until it calls the returned
+// func.
+func (l *Limiter) enqueue(n int64) (dequeue func()) {
+	waiter := &Waiter{Bytes: n, Since: time.Now()}
+
+	l.waiters.mu.Lock()
+	if l.waiters.waiting == nil {
+		l.waiters.waiting = make(map[*Waiter]struct{})
+	}
+	l.waiters.waiting[waiter] = struct{}{}
+	l.waiters.mu.Unlock()
+
+	return func() {
+		l.waiters.mu.Lock()
+		delete(l.waiters.waiting, waiter)
+		l.waiters.mu.Unlock()
+	}
+}
+
+// sleep sleeps for d as a waiter for n bytes.
+func (l *Limiter) sleep(d time.Duration, n int64) {
+	defer l.enqueue(n)()
+	time.Sleep(d)
+}
@@ -0,0 +1,142 @@
+package ratelimitedreader
+
+import "io"
+
+// Wrap rate limits reader like NewRateLimitedReader, but the returned reader
+// keeps implementing whichever of io.Seeker, io.ReaderAt and io.WriterTo
+// reader implements, so wrapping doesn't hide them from io.Copy,
+// http.ServeContent and the like. ReadAt and WriteTo are paced by the same
+// limiter as Read; Seek moves no data, so it isn't, and isn't meant to be
+// combined with read-ahead. Close closes reader if it's an io.Closer.
+// Assert the result to the interfaces needed, e.g.
+// Wrap(file, limit).(io.ReadSeeker).
+func Wrap(reader io.Reader, limit int64, opts ...Option) io.ReadCloser {
+	readCloser, ok := reader.(io.ReadCloser)
+	if !ok {
+		readCloser = io.NopCloser(reader)
+	}
+	r := NewRateLimitedReadCloser(readCloser, limit, opts...)
+
+	seeker, isSeeker := reader.(io.Seeker)
+	readerAt, isReaderAt := reader.(io.ReaderAt)
+	_, isWriterTo := reader.(io.WriterTo)
+
+	seek := pacedSeeker{r: r, seeker: seeker}
+	at := pacedReaderAt{r: r, readerAt: readerAt}
+	to := pacedWriterTo{r: r}
+	switch {
+	case isSeeker && isReaderAt && isWriterTo:
+		return struct {
+			*RateLimitedReader
+			pacedSeeker
+			pacedReaderAt
+			pacedWriterTo
+		}{r, seek, at, to}
+	case isSeeker && isReaderAt:
+		return struct {
+			*RateLimitedReader
+			pacedSeeker
+			pacedReaderAt
+		}{r, seek, at}
+	case isSeeker && isWriterTo:
+		return struct {
+			*RateLimitedReader
+			pacedSeeker
+			pacedWriterTo
+		}{r, seek, to}
+	case isReaderAt && isWriterTo:
+		return struct {
+			*RateLimitedReader
+			pacedReaderAt
+			pacedWriterTo
+		}{r, at, to}
+	case isSeeker:
+		return struct {
+			*RateLimitedReader
+			pacedSeeker
+		}{r, seek}
+	case isReaderAt:
+		return struct {
+			*RateLimitedReader
+			pacedReaderAt
+		}{r, at}
+	case isWriterTo:
+		return struct {
+			*RateLimitedReader
+			pacedWriterTo
+		}{r, to}
+	default:
+		return r
+	}
+}
+
+// pacedSeeker seeks seeker, which moves no data so costs no budget, keeping
+// r in sync with the new position.
+type pacedSeeker struct {
+	r      *RateLimitedReader
+	seeker io.Seeker
+}
+
+func (p pacedSeeker) Seek(offset int64, whence int) (int64, error) {
+	n, err := p.seeker.Seek(offset, whence)
+	p.r.seeked()
+	return n, err
+}
+
+// pacedReaderAt reads from readerAt at r's limited rate.
+type pacedReaderAt struct {
+	r        *RateLimitedReader
+	readerAt io.ReaderAt
+}
+
+func (p pacedReaderAt) ReadAt(b []byte, off int64) (n int, err error) {
+	for n < len(b) {
+		limit := p.r.limiter.iterLimit()
+		if limit <= 0 {
+			m, err := p.readerAt.ReadAt(b[n:], off+int64(n))
+			return n + m, err
+		}
+
+		allowedBytes := min(limit, int64(len(b)-n))
+		if err := p.r.sleep(allowedBytes, limit); err != nil {
+			return n, err
+		}
+
+		m, err := p.readerAt.ReadAt(b[n:n+int(allowedBytes)], off+int64(n))
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// pacedWriterTo writes r's data to a writer through r's paced Read.
+type pacedWriterTo struct {
+	r *RateLimitedReader
+}
+
+func (p pacedWriterTo) WriteTo(w io.Writer) (n int64, err error) {
+	buf := make([]byte, 32*1024)
+	for {
+		m, readErr := p.r.Read(buf)
+		if m > 0 {
+			written, writeErr := w.Write(buf[:m])
+			n += int64(written)
+			if writeErr != nil {
+				return n, writeErr
+			}
+			if written != m {
+				return n, io.ErrShortWrite
+			}
+		}
+
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
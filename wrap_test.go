@@ -0,0 +1,106 @@
+package ratelimitedreader
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrap_PreservesInterfaces(t *testing.T) {
+	wrapped := Wrap(bytes.NewReader(nil), 1024)
+	if _, ok := wrapped.(io.Seeker); !ok {
+		t.Fatalf("expected wrapped bytes.Reader to be an io.Seeker")
+	}
+	if _, ok := wrapped.(io.ReaderAt); !ok {
+		t.Fatalf("expected wrapped bytes.Reader to be an io.ReaderAt")
+	}
+	if _, ok := wrapped.(io.WriterTo); !ok {
+		t.Fatalf("expected wrapped bytes.Reader to be an io.WriterTo")
+	}
+
+	plain := Wrap(infiniteReader{}, 1024)
+	if _, ok := plain.(*RateLimitedReader); !ok {
+		t.Fatalf("expected a plain reader to be wrapped in a RateLimitedReader, got %T", plain)
+	}
+
+	seekOnly := Wrap(struct{ io.ReadSeeker }{bytes.NewReader(nil)}, 1024)
+	if _, ok := seekOnly.(io.ReaderAt); ok {
+		t.Fatalf("unexpected io.ReaderAt on a wrapped reader that isn't one")
+	}
+	if _, ok := seekOnly.(io.Seeker); !ok {
+		t.Fatalf("expected wrapped io.ReadSeeker to be an io.Seeker")
+	}
+}
+
+func TestWrap_Read(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const bufferSize = 1024
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	wrapped := Wrap(bytes.NewReader(make([]byte, dataSize)), limit)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, struct{ io.Reader }{wrapped})
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected copy, read: %d err: %v expected: %d", n, err, dataSize)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestWrap_Seek(t *testing.T) {
+	const limit = 1024
+
+	key := "0123456789"
+	wrapped := Wrap(strings.NewReader(key), limit)
+	if _, err := wrapped.(io.Seeker).Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := io.ReadAll(wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != key[5:] {
+		t.Fatalf("read incorrect data after seek, read: %s expected: %s", data, key[5:])
+	}
+}
+
+func TestWrap_ReadAt(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	data := strings.Repeat("0123456789", dataSize/10+1)
+	wrapped := Wrap(strings.NewReader(data), limit)
+
+	buffer := make([]byte, dataSize)
+	start := time.Now()
+	n, err := wrapped.(io.ReaderAt).ReadAt(buffer, 10)
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected read at, read: %d err: %v expected: %d", n, err, dataSize)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if string(buffer) != data[10:10+dataSize] {
+		t.Fatalf("read incorrect data at offset")
+	}
+}
+
+func TestWrap_WriteTo(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	wrapped := Wrap(bytes.NewReader(make([]byte, dataSize)), limit)
+
+	start := time.Now()
+	// io.Copy goes through WriteTo, which must not bypass the limit
+	n, err := io.Copy(io.Discard, wrapped)
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected copy, read: %d err: %v expected: %d", n, err, dataSize)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
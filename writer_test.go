@@ -0,0 +1,110 @@
+package ratelimitedreader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _ io.StringWriter = (*RateLimitedWriter)(nil)
+
+func TestRateLimitedWriter_Write(t *testing.T) {
+	const dataSize = 40 * 1024 // 40KB
+	const partsAmount = 2
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var dst bytes.Buffer
+	ratelimitedWriter := NewRateLimitedWriter(&dst, limit)
+
+	start := time.Now()
+	n, err := ratelimitedWriter.Write(make([]byte, dataSize))
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected write, wrote: %d error: %v", n, err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if dst.Len() != dataSize {
+		t.Fatalf("unexpected written data size: %d expected: %d", dst.Len(), dataSize)
+	}
+}
+
+func TestRateLimitedWriter_WriteString(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var dst strings.Builder
+	ratelimitedWriter := NewRateLimitedWriter(&dst, limit)
+	data := strings.Repeat("A", dataSize)
+
+	start := time.Now()
+	n, err := ratelimitedWriter.WriteString(data)
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected write, wrote: %d error: %v", n, err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if dst.String() != data {
+		t.Fatalf("written data differs")
+	}
+}
+
+func TestRateLimitedWriter_FlushesBufferedWriter(t *testing.T) {
+	const dataSize = 1024
+	const limit = dataSize * 20
+
+	var dst bytes.Buffer
+	buffered := bufio.NewWriterSize(&dst, dataSize*4)
+	ratelimitedWriter := NewRateLimitedWriter(buffered, limit)
+
+	if _, err := ratelimitedWriter.Write(make([]byte, dataSize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// paced bytes must leave the buffer rather than wait for a flush
+	if dst.Len() != dataSize {
+		t.Fatalf("unexpected flushed data size: %d expected: %d", dst.Len(), dataSize)
+	}
+}
+
+func TestRateLimitedWriter_UnderBufferedWriter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	var dst bytes.Buffer
+	buffered := bufio.NewWriterSize(NewRateLimitedWriter(&dst, limit), dataSize)
+
+	start := time.Now()
+	buffered.Write(make([]byte, dataSize))
+	if err := buffered.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+}
+
+func TestMultiWriter(t *testing.T) {
+	const dataSize = 20 * 1024 // 20KB
+	const partsAmount = 1
+	const limit = dataSize / partsAmount // dataSize/partsAmount bytes per second
+
+	// three sinks take as long as one
+	var plain bytes.Buffer
+	var buffered bytes.Buffer
+	bufferedSink := bufio.NewWriterSize(&buffered, dataSize*2)
+	ratelimitedWriter := NewMultiWriter(limit, &plain, bufferedSink, io.Discard)
+
+	start := time.Now()
+	n, err := ratelimitedWriter.Write(make([]byte, dataSize))
+	if err != nil || n != dataSize {
+		t.Fatalf("unexpected write, wrote: %d error: %v", n, err)
+	}
+	assertReadTimes(t, time.Since(start), partsAmount, partsAmount+1)
+
+	if plain.Len() != dataSize || buffered.Len() != dataSize {
+		t.Fatalf("unexpected written data sizes: %d, %d expected: %d", plain.Len(), buffered.Len(), dataSize)
+	}
+}